@@ -13,7 +13,9 @@ require github.com/BurntSushi/toml v1.3.2
 
 require github.com/go-chi/cors v1.2.1
 
-require github.com/mitchellh/mapstructure v1.5.0 // indirect
+require github.com/mitchellh/mapstructure v1.5.0
+
+require github.com/bmatcuk/doublestar/v4 v4.9.1
 
 require (
 	github.com/spf13/afero v1.11.0
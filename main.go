@@ -15,6 +15,11 @@ func main() {
 	}
 
 	rootInst.RegisterDataSourceProvider(&sulat.FileDataSourceProvider{})
+	rootInst.RegisterDataSourceProvider(&sulat.SQLiteDataSourceProvider{})
+	rootInst.RegisterDataSourceProvider(&sulat.GitDataSourceProvider{})
+	rootInst.RegisterDataSourceProvider(&sulat.RESTDataSourceProvider{})
+	rootInst.RegisterDataSourceProvider(&sulat.MemoryDataSourceProvider{})
+	rootInst.RegisterDataSourceProvider(&sulat.CompositeDataSourceProvider{})
 
 	if err := server.Start(rootInst, "3000"); err != nil {
 		log.Fatalf("failed to start server: %s\n", err)
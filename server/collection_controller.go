@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/nedpals/sulatcms/sulat"
+	"github.com/nedpals/sulatcms/sulat/query"
 )
 
 type CollectionController struct {
@@ -28,6 +29,12 @@ func NewCollectionController() *CollectionController {
 			sr.Get("/", wrapHandler(r.getSchema))
 			sr.Patch("/", wrapHandler(r.updateSchema))
 		})
+		sr.Route("/queries", func(sr chi.Router) {
+			sr.Get("/", wrapHandler(r.getSavedQueries))
+			sr.Get("/{queryName}", wrapHandler(r.getSavedQuery))
+			sr.Put("/{queryName}", wrapHandler(r.saveQuery))
+			sr.Delete("/{queryName}", wrapHandler(r.removeSavedQuery))
+		})
 		sr.Mount("/records", NewRecordController())
 	})
 
@@ -95,6 +102,15 @@ func (c *CollectionController) removeCollection(w http.ResponseWriter, r *http.R
 
 func (c *CollectionController) getSchema(w http.ResponseWriter, r *http.Request) error {
 	collection := getCurrentCollection(r)
+	if len(collection.Schema) == 0 && collection.Source != nil {
+		// collections without a schema use the schema inferred from
+		// their records if the data source supports it
+		if inferrer, ok := collection.Source.DataSourceProvider.(sulat.DataSourceSchemaInferrer); ok {
+			if schema, err := inferrer.InferSchema(collection.Id); err == nil {
+				return returnJson(w, schema)
+			}
+		}
+	}
 	return returnJson(w, collection.Schema)
 }
 
@@ -107,3 +123,70 @@ func (c *CollectionController) updateSchema(w http.ResponseWriter, r *http.Reque
 	collection.Schema = schema
 	return returnJson(w, collection.Schema)
 }
+
+func (c *CollectionController) getSavedQueries(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	savedQueries, err := collection.SavedQueries()
+	if err != nil {
+		return err
+	}
+	return returnJson(w, savedQueries)
+}
+
+func (c *CollectionController) getSavedQuery(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	savedQuery, err := collection.FindSavedQuery(chi.URLParam(r, "queryName"))
+	if err != nil {
+		return err
+	}
+
+	params, err := savedQuery.Parameters()
+	if err != nil {
+		return err
+	}
+
+	return returnJsonWithMeta(w, savedQuery, map[string]any{
+		"parameters": params,
+	})
+}
+
+// saveQuery creates or replaces a saved query. The query in the payload
+// may either be in its textual or JSON representation.
+func (c *CollectionController) saveQuery(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	payload := struct {
+		Query json.RawMessage `json:"query"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return sulat.NewResponseError(http.StatusBadRequest, err.Error())
+	}
+
+	var rawQuery string
+	var q *query.Query
+	var err error
+
+	if json.Unmarshal(payload.Query, &rawQuery) == nil {
+		q, err = query.ParseFromString(rawQuery)
+	} else {
+		q, err = query.ParseFromJSON(payload.Query)
+	}
+
+	if err != nil {
+		return queryParseError(err)
+	}
+
+	savedQuery, err := collection.SaveQuery(chi.URLParam(r, "queryName"), q)
+	if err != nil {
+		return err
+	}
+	return returnJson(w, savedQuery)
+}
+
+func (c *CollectionController) removeSavedQuery(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	if err := collection.RemoveSavedQuery(chi.URLParam(r, "queryName")); err != nil {
+		return err
+	}
+	return returnJson(w, nil)
+}
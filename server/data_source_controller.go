@@ -22,6 +22,7 @@ func NewDataSourceController() *DataSourceController {
 	r.Route("/{dataSourceId}", func(sr chi.Router) {
 		sr.Use(getDataSourceCtx)
 		sr.Get("/", wrapHandler(r.getDataSource))
+		sr.Get("/status", wrapHandler(r.getDataSourceStatus))
 		sr.Delete("/", wrapHandler(r.removeDataSource))
 		sr.With(validateRequest[sulat.DataSource]()).Patch("/", wrapHandler(r.updateDataSource))
 	})
@@ -63,6 +64,11 @@ func (c *DataSourceController) getDataSource(w http.ResponseWriter, r *http.Requ
 	return returnJson(w, dataSource)
 }
 
+func (c *DataSourceController) getDataSourceStatus(w http.ResponseWriter, r *http.Request) error {
+	dataSource := getCurrentDataSource(r)
+	return returnJson(w, dataSource.Status())
+}
+
 func (c *DataSourceController) removeDataSource(w http.ResponseWriter, r *http.Request) error {
 	inst := getCurrentInstance(r)
 	dataSource := getCurrentDataSource(r)
@@ -80,8 +86,25 @@ func (c *DataSourceController) updateDataSource(w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	dataSource.Id = validated.Id
-	dataSource.Name = validated.Name
+	// the changes are validated on a copy so rejected updates do not
+	// leave the running data source with an invalid config
+	updated := *dataSource
+	updated.Id = validated.Id
+	updated.Name = validated.Name
+	updated.ProviderId = validated.ProviderId
+	if validated.Config != nil {
+		updated.Config = validated.Config
+	}
+
+	if err := inst.ValidateDataSource(&updated); err != nil {
+		return err
+	}
+
+	dataSource.Id = updated.Id
+	dataSource.Name = updated.Name
+	dataSource.ProviderId = updated.ProviderId
+	dataSource.Config = updated.Config
+
 	if err := inst.UpdateDataSource(dataSource); err != nil {
 		return err
 	}
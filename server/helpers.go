@@ -3,12 +3,16 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mitchellh/mapstructure"
 	"github.com/nedpals/sulatcms/sulat"
 	"github.com/nedpals/sulatcms/sulat/query"
+	"golang.org/x/exp/slices"
 )
 
 func returnJson(wr http.ResponseWriter, data any) error {
@@ -18,6 +22,14 @@ func returnJson(wr http.ResponseWriter, data any) error {
 	})
 }
 
+func returnJsonWithMeta(wr http.ResponseWriter, data any, meta map[string]any) error {
+	wr.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(wr).Encode(map[string]any{
+		"data": data,
+		"meta": meta,
+	})
+}
+
 type HandlerFunc func(http.ResponseWriter, *http.Request) error
 
 func wrapHandler(fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
@@ -25,19 +37,27 @@ func wrapHandler(fn func(http.ResponseWriter, *http.Request) error) http.Handler
 		if err := fn(w, r); err != nil {
 			statusCode := http.StatusInternalServerError
 			errorMessage := err.Error()
+			var errorDetails any
 
 			if handlerErr, ok := err.(*sulat.ResponseError); ok {
 				statusCode = handlerErr.StatusCode
 				errorMessage = handlerErr.Message
+				errorDetails = handlerErr.Details
+			}
+
+			errorPayload := map[string]any{
+				"status":  statusCode,
+				"message": errorMessage,
+			}
+
+			if errorDetails != nil {
+				errorPayload["details"] = errorDetails
 			}
 
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(statusCode)
 			json.NewEncoder(w).Encode(map[string]any{
-				"error": map[string]any{
-					"status":  statusCode,
-					"message": errorMessage,
-				},
+				"error": errorPayload,
 			})
 		}
 	}
@@ -126,11 +146,21 @@ func getDataSourceCtx(next http.Handler) http.Handler {
 
 type currentRecordCtx struct{}
 
+// getRecordId returns the record id from the URL. Ids of nested records
+// contain slashes and are passed as escaped path segments (e.g.
+// posts%2F2023%2Fintro.md) which are kept escaped by the router.
+func getRecordId(r *http.Request) string {
+	recordId := chi.URLParam(r, "recordId")
+	if unescaped, err := url.PathUnescape(recordId); err == nil {
+		return unescaped
+	}
+	return recordId
+}
+
 func getRecordCtx(next http.Handler) http.Handler {
 	return wrapHandler(func(w http.ResponseWriter, r *http.Request) error {
 		collection := getCurrentCollection(r)
-		recordId := chi.URLParam(r, "recordId")
-		record, err := collection.Source.Get(collection.Id, recordId, nil)
+		record, err := collection.Source.Get(collection.Id, getRecordId(r), nil)
 		if err != nil {
 			return err
 		}
@@ -144,16 +174,107 @@ type currentQueryCtx struct{}
 
 func getQueryCtx(next http.Handler) http.Handler {
 	return wrapHandler(func(w http.ResponseWriter, r *http.Request) error {
+		if r.URL.Query().Has("saved") {
+			query, err := getSavedQuery(r)
+			if err != nil {
+				return err
+			}
+			return serveWithQuery(next, w, r, query)
+		}
+
 		query, err := query.ParseFromRequest(r)
+		if err != nil {
+			return queryParseError(err)
+		}
+		return serveWithQuery(next, w, r, query)
+	})
+}
+
+// reservedQueryParams are the URL parameters which are not passed
+// as parameters of a saved query
+var reservedQueryParams = []string{"q", "saved", "cursor"}
+
+// getSavedQuery finds the saved query of the current collection named in
+// the saved URL parameter and binds the remaining URL parameters into it
+func getSavedQuery(r *http.Request) (*query.Query, error) {
+	values := r.URL.Query()
+	if values.Has("q") {
+		return nil, sulat.NewResponseError(http.StatusBadRequest, "q and saved cannot be used together")
+	}
+
+	collection, ok := r.Context().Value(currentCollectionCtx{}).(*sulat.Collection)
+	if !ok {
+		return nil, sulat.NewResponseError(http.StatusBadRequest, "saved queries are only available within a collection")
+	}
+
+	savedQuery, err := collection.FindSavedQuery(values.Get("saved"))
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]any{}
+	for key := range values {
+		if !slices.Contains(reservedQueryParams, key) {
+			params[key] = decodeParamValue(values.Get(key))
+		}
+	}
+
+	return savedQuery.Bind(params)
+}
+
+// decodeParamValue decodes the URL parameter as a JSON value (eg. numbers,
+// booleans and arrays) and falls back to the raw string otherwise
+func decodeParamValue(raw string) any {
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return raw
+	}
+	return value
+}
+
+// getJSONQueryCtx is the same as getQueryCtx but decodes the query
+// from the JSON representation in the request body
+func getJSONQueryCtx(next http.Handler) http.Handler {
+	return wrapHandler(func(w http.ResponseWriter, r *http.Request) error {
+		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			return err
 		}
 
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), currentQueryCtx{}, query)))
-		return nil
+		query, err := query.ParseFromJSON(body)
+		if err != nil {
+			return queryParseError(err)
+		}
+		return serveWithQuery(next, w, r, query)
 	})
 }
 
+func serveWithQuery(next http.Handler, w http.ResponseWriter, r *http.Request, query *query.Query) error {
+	if cursor := r.URL.Query().Get("cursor"); len(cursor) != 0 {
+		query = query.WithOption("cursor", cursor)
+	}
+
+	if err := sulat.ValidateQuery(query); err != nil {
+		return err
+	}
+
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), currentQueryCtx{}, query)))
+	return nil
+}
+
+// queryParseError converts the query parsing error into a bad request
+func queryParseError(err error) error {
+	switch err := err.(type) {
+	case *query.ParseError:
+		return sulat.NewResponseErrorWithDetails(http.StatusBadRequest, err.Error(), err)
+	case *query.ValidationError:
+		return sulat.NewResponseErrorWithDetails(http.StatusBadRequest, "invalid query", query.ValidationErrors{err})
+	}
+	return sulat.NewResponseError(http.StatusBadRequest, err.Error())
+}
+
 func getDecodedQuery(r *http.Request) *query.Query {
 	return r.Context().Value(currentQueryCtx{}).(*query.Query)
 }
@@ -178,8 +299,14 @@ func validateRecord(next http.Handler) http.Handler {
 			return err
 		}
 
+		// the id in the URL takes precedence over the id in the payload
+		recordId, _ := payload["id"].(string)
+		if urlRecordId := getRecordId(r); len(urlRecordId) != 0 {
+			recordId = urlRecordId
+		}
+
 		record := &sulat.Record{
-			Id:         payload["id"].(string),
+			Id:         recordId,
 			Data:       payload,
 			Collection: collection,
 		}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nedpals/sulatcms/sulat"
 	"github.com/nedpals/sulatcms/sulat/query"
 )
 
@@ -17,9 +18,14 @@ func NewRecordController() *RecordController {
 	}
 
 	r.With(getQueryCtx).Get("/", wrapHandler(r.getRecords))
+	r.With(getJSONQueryCtx).Post("/query", wrapHandler(r.getRecords))
+	r.Get("/explain", wrapHandler(r.explainQuery))
 	r.With(validateRecord).Post("/", wrapHandler(r.createRecord))
 	r.With(getRecordCtx).Delete("/{recordId}", wrapHandler(r.deleteRecord))
 	r.With(validateRecord).Patch("/{recordId}", wrapHandler(r.updateRecord))
+	r.Get("/{recordId}/history", wrapHandler(r.getRecordHistory))
+	r.Get("/{recordId}/history/{revision}", wrapHandler(r.getRecordDiff))
+	r.Post("/{recordId}/history/{revision}/restore", wrapHandler(r.restoreRecord))
 
 	return r
 }
@@ -28,11 +34,97 @@ func (rc *RecordController) getRecords(w http.ResponseWriter, r *http.Request) e
 	collection := getCurrentCollection(r)
 	query := getDecodedQuery(r)
 
-	records, err := collection.Find(query, nil)
+	page, err := collection.FindPage(query, nil)
 	if err != nil {
 		return err
 	}
-	return returnJson(w, records)
+
+	opts, err := query.ParseOptions()
+	if err != nil {
+		return err
+	}
+
+	nextCursor, prevCursor := pageCursors(page.Records, opts)
+	meta := map[string]any{
+		"total":  page.Total,
+		"limit":  opts.Limit,
+		"offset": opts.Offset,
+		"cursors": map[string]any{
+			"next": nextCursor,
+			"prev": prevCursor,
+		},
+		"links": map[string]any{
+			"next": cursorLink(r, nextCursor),
+			"prev": cursorLink(r, prevCursor),
+		},
+	}
+
+	if page.Aggregates != nil {
+		meta["aggregates"] = page.Aggregates
+	}
+
+	return returnJsonWithMeta(w, page.Records, meta)
+}
+
+// pageCursors returns the cursor tokens pointing to the neighbouring pages
+// of the current page. A cursor is only returned if the page may have a
+// neighbouring page on that side.
+func pageCursors(records []*sulat.Record, opts query.Options) (next string, prev string) {
+	if len(records) == 0 {
+		return "", ""
+	}
+
+	isFull := opts.Limit > 0 && len(records) == opts.Limit
+	isBefore := opts.Cursor != nil && opts.Cursor.Before
+	isAfter := opts.Cursor != nil && !opts.Cursor.Before
+
+	if isFull || isBefore {
+		next = query.NewCursor(records[len(records)-1], opts.Order, false).Encode()
+	}
+
+	if isAfter || opts.Offset > 0 || (isBefore && isFull) {
+		prev = query.NewCursor(records[0], opts.Order, true).Encode()
+	}
+	return
+}
+
+func cursorLink(r *http.Request, cursor string) any {
+	if len(cursor) == 0 {
+		return nil
+	}
+
+	link := *r.URL
+	values := link.Query()
+	values.Set("cursor", cursor)
+	link.RawQuery = values.Encode()
+	return link.RequestURI()
+}
+
+// explainQuery returns the parsed tree of the query along with its
+// normalized form and validation errors
+func (rc *RecordController) explainQuery(w http.ResponseWriter, r *http.Request) error {
+	q, err := query.ParseFromRequest(r)
+	if err != nil {
+		return queryParseError(err)
+	} else if q == nil {
+		return sulat.NewResponseError(http.StatusBadRequest, "query is required")
+	}
+
+	var validationErrors query.ValidationErrors
+	if err := q.Validate(); err != nil {
+		if errs, ok := err.(query.ValidationErrors); ok {
+			validationErrors = errs
+		} else {
+			return sulat.NewResponseError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	return returnJson(w, map[string]any{
+		"query":      q,
+		"normalized": q.String(),
+		"valid":      len(validationErrors) == 0,
+		"errors":     validationErrors,
+	})
 }
 
 func (rc *RecordController) createRecord(w http.ResponseWriter, r *http.Request) error {
@@ -62,3 +154,35 @@ func (rc *RecordController) updateRecord(w http.ResponseWriter, r *http.Request)
 	}
 	return returnJson(w, record)
 }
+
+func (rc *RecordController) getRecordHistory(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	revisions, err := collection.History(getRecordId(r), nil)
+	if err != nil {
+		return err
+	}
+	return returnJson(w, revisions)
+}
+
+func (rc *RecordController) getRecordDiff(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	revision := chi.URLParam(r, "revision")
+	diff, err := collection.Diff(getRecordId(r), revision, nil)
+	if err != nil {
+		return err
+	}
+
+	return returnJson(w, map[string]any{
+		"revision": revision,
+		"diff":     diff,
+	})
+}
+
+func (rc *RecordController) restoreRecord(w http.ResponseWriter, r *http.Request) error {
+	collection := getCurrentCollection(r)
+	record, err := collection.Restore(getRecordId(r), chi.URLParam(r, "revision"), nil)
+	if err != nil {
+		return err
+	}
+	return returnJson(w, record)
+}
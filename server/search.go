@@ -1,6 +1,11 @@
 package server
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nedpals/sulatcms/sulat"
+)
 
 type SiteSearchResultDataType string
 
@@ -23,6 +28,10 @@ type SiteSearchResult struct {
 
 func searchWithinSite(w http.ResponseWriter, r *http.Request) error {
 	site := getCurrentSite(r)
+	if r.URL.Query().Has("text") {
+		return searchTextWithinSite(w, r)
+	}
+
 	query := getDecodedQuery(r)
 	results := SiteSearchResult{
 		Collections: []SiteSearchResultData{},
@@ -55,3 +64,50 @@ func searchWithinSite(w http.ResponseWriter, r *http.Request) error {
 	results.Total = len(results.Collections) + len(results.Records)
 	return returnJson(w, results)
 }
+
+// searchTextWithinSite returns the ranked records of the site matching
+// the text parameter using the full-text search index
+func searchTextWithinSite(w http.ResponseWriter, r *http.Request) error {
+	site := getCurrentSite(r)
+	params := r.URL.Query()
+
+	limit, err := getIntParam(r, "limit")
+	if err != nil {
+		return err
+	}
+
+	offset, err := getIntParam(r, "offset")
+	if err != nil {
+		return err
+	}
+
+	opts := sulat.SearchOptions{
+		Limit:       limit,
+		Offset:      offset,
+		Collections: params["collection"],
+	}
+
+	results, err := site.Search(params.Get("text"), opts)
+	if err != nil {
+		return err
+	}
+
+	return returnJsonWithMeta(w, results.Hits, map[string]any{
+		"total":  results.Total,
+		"limit":  results.Limit,
+		"offset": results.Offset,
+	})
+}
+
+func getIntParam(r *http.Request, key string) (int, error) {
+	rawValue := r.URL.Query().Get(key)
+	if len(rawValue) == 0 {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(rawValue)
+	if err != nil || value < 0 {
+		return 0, sulat.NewResponseError(http.StatusBadRequest, key+" must be a non-negative integer")
+	}
+	return value, nil
+}
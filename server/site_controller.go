@@ -20,6 +20,7 @@ func NewSiteController() *SiteController {
 		sr.Use(getSiteCtx)
 		sr.Get("/", wrapHandler(r.getSite))
 		sr.With(getQueryCtx).Get("/search", wrapHandler(searchWithinSite))
+		sr.Post("/search/reindex", wrapHandler(r.reindexSite))
 		sr.Mount("/collections", NewCollectionController())
 	})
 
@@ -39,3 +40,11 @@ func (c *SiteController) getSite(w http.ResponseWriter, r *http.Request) error {
 	site := getCurrentSite(r)
 	return returnJson(w, site)
 }
+
+func (c *SiteController) reindexSite(w http.ResponseWriter, r *http.Request) error {
+	site := getCurrentSite(r)
+	if err := site.Reindex(); err != nil {
+		return err
+	}
+	return returnJson(w, nil)
+}
@@ -1,6 +1,8 @@
 package sulat
 
 import (
+	"net/http"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/nedpals/sulatcms/sulat/query"
 )
@@ -69,19 +71,224 @@ func (c *Collection) Find(query *query.Query, opts map[string]any) ([]*Record, e
 	return c.Source.Find(c.Id, query, opts)
 }
 
+// Count counts the records from the collection matching the query
+// regardless of its pagination options
+func (c *Collection) Count(q *query.Query, opts map[string]any) (int, error) {
+	if counter, ok := c.Source.DataSourceProvider.(DataSourceCounter); ok {
+		return counter.Count(c.Id, q, opts)
+	}
+
+	records, err := c.Source.Find(c.Id, q.WithoutPagination(), opts)
+	if rErr, ok := err.(*ResponseError); ok && rErr.StatusCode == http.StatusNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// Aggregate computes the aggregations requested in the query options
+// over all of the records matching the query. A nil result is returned
+// if the query does not request any aggregation.
+func (c *Collection) Aggregate(q *query.Query, opts map[string]any) (map[string]any, error) {
+	queryOpts, err := q.ParseOptions()
+	if err != nil {
+		return nil, NewResponseError(http.StatusBadRequest, err.Error())
+	} else if !queryOpts.HasAggregations() {
+		return nil, nil
+	}
+
+	records, err := c.Source.Find(c.Id, q.WithoutAggregations(), opts)
+	if rErr, ok := err.(*ResponseError); ok && rErr.StatusCode == http.StatusNotFound {
+		records = []*Record{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return query.Aggregate(queryOpts, records), nil
+}
+
+// RecordPage is a page of the records matching a query
+type RecordPage struct {
+	Records []*Record
+
+	// Total is the number of records matching the query regardless of
+	// its pagination options
+	Total int
+
+	// Aggregates are the aggregations requested in the query options.
+	// It is nil if the query does not request any aggregation.
+	Aggregates map[string]any
+}
+
+// FindPage finds the page of records matching the query together with the
+// total number of matching records and the aggregations. The records are
+// matched only once and the options of the query are applied afterwards.
+func (c *Collection) FindPage(q *query.Query, opts map[string]any) (*RecordPage, error) {
+	queryOpts, err := q.ParseOptions()
+	if err != nil {
+		return nil, NewResponseError(http.StatusBadRequest, err.Error())
+	}
+
+	matched, err := c.Source.Find(c.Id, q.WithoutAggregations(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := ApplyQueryOptions(q, matched)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &RecordPage{
+		Records: records,
+		Total:   len(matched),
+	}
+
+	if queryOpts.HasAggregations() {
+		page.Aggregates = query.Aggregate(queryOpts, matched)
+	}
+	return page, nil
+}
+
 // Insert inserts a record into the collection
 func (c *Collection) Insert(record *Record, opts map[string]any) error {
-	return c.Source.Insert(c.Id, record, opts)
+	if err := c.Source.Insert(c.Id, record, opts); err != nil {
+		return err
+	}
+
+	c.indexChanges([]*Record{record}, nil)
+	return nil
 }
 
 // Update updates a record from the collection
 func (c *Collection) Update(record *Record, opts map[string]any) error {
-	return c.Source.Update(c.Id, record, opts)
+	if err := c.Source.Update(c.Id, record, opts); err != nil {
+		return err
+	}
+
+	c.indexChanges([]*Record{record}, nil)
+	return nil
 }
 
 // Delete deletes a record from the collection
-func (c *Collection) Delete(query *query.Query, opts map[string]any) error {
-	return c.Source.Delete(c.Id, query, opts)
+func (c *Collection) Delete(q *query.Query, opts map[string]any) error {
+	idx := c.searchIndex()
+	if idx == nil {
+		return c.Source.Delete(c.Id, q, opts)
+	}
+
+	// find the records to be deleted beforehand so that they can be
+	// removed from the search index
+	records, err := c.Source.Find(c.Id, q.WithoutPagination(), opts)
+	if rErr, ok := err.(*ResponseError); ok && rErr.StatusCode == http.StatusNotFound {
+		records = []*Record{}
+	} else if err != nil {
+		return err
+	}
+
+	if err := c.Source.Delete(c.Id, q, opts); err != nil {
+		return err
+	}
+
+	recordIds := make([]string, len(records))
+	for i, record := range records {
+		recordIds[i] = record.Id
+	}
+
+	c.indexChanges(nil, recordIds)
+	return nil
+}
+
+func (c *Collection) history() (DataSourceHistory, error) {
+	if history, ok := c.Source.DataSourceProvider.(DataSourceHistory); ok {
+		return history, nil
+	}
+	return nil, NewResponseError(http.StatusNotImplemented, "data source does not keep the history of records")
+}
+
+// History returns the revisions of a record of the collection
+func (c *Collection) History(id string, opts map[string]any) ([]*RecordRevision, error) {
+	history, err := c.history()
+	if err != nil {
+		return nil, err
+	}
+	return history.History(c.Id, id, opts)
+}
+
+// Diff returns the changes made to a record of the collection in the revision
+func (c *Collection) Diff(id string, revision string, opts map[string]any) (string, error) {
+	history, err := c.history()
+	if err != nil {
+		return "", err
+	}
+	return history.Diff(c.Id, id, revision, opts)
+}
+
+// Restore reverts a record of the collection to its state in the revision
+func (c *Collection) Restore(id string, revision string, opts map[string]any) (*Record, error) {
+	history, err := c.history()
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := history.Restore(c.Id, id, revision, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.indexChanges([]*Record{record}, nil)
+	return record, nil
+}
+
+// SavedQueries returns the saved queries of the collection
+func (c *Collection) SavedQueries() ([]*SavedQuery, error) {
+	savedQueries := []*SavedQuery{}
+	if err := fetchSavedQueries(&savedQueries, c.Id, c.site.instance.db); err != nil {
+		return nil, err
+	}
+	return savedQueries, nil
+}
+
+// FindSavedQuery finds a saved query of the collection by name
+func (c *Collection) FindSavedQuery(name string) (*SavedQuery, error) {
+	savedQuery := &SavedQuery{}
+	if err := fetchSavedQuery(savedQuery, c.Id, name, c.site.instance.db); err != nil {
+		return nil, err
+	}
+	return savedQuery, nil
+}
+
+// SaveQuery saves the query under the given name. An existing saved
+// query with the same name is replaced.
+func (c *Collection) SaveQuery(name string, q *query.Query) (*SavedQuery, error) {
+	if len(name) == 0 {
+		return nil, NewResponseError(http.StatusBadRequest, "saved query name is required")
+	} else if q == nil {
+		return nil, NewResponseError(http.StatusBadRequest, "query is required")
+	} else if err := ValidateQuery(q); err != nil {
+		return nil, err
+	}
+
+	savedQuery := &SavedQuery{
+		Name:         name,
+		CollectionId: c.Id,
+		Query:        q.String(),
+	}
+
+	if err := upsertSavedQuery(savedQuery, c.site.instance.db); err != nil {
+		return nil, err
+	}
+	return savedQuery, nil
+}
+
+// RemoveSavedQuery removes a saved query from the collection
+func (c *Collection) RemoveSavedQuery(name string) error {
+	savedQuery, err := c.FindSavedQuery(name)
+	if err != nil {
+		return err
+	}
+	return removeSavedQuery(savedQuery, c.site.instance.db)
 }
 
 func fetchCollections(collections *[]*Collection, db *sqlx.DB) error {
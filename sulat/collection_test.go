@@ -0,0 +1,67 @@
+package sulat
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+func TestCollectionFindPage(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := []*Record{}
+	for i := 1; i <= 5; i++ {
+		category := "news"
+		if i%2 == 0 {
+			category = "blog"
+		}
+		posts = append(posts, &Record{Id: fmt.Sprintf("post-%d", i), Data: map[string]any{"category": category, "views": i * 10}})
+	}
+
+	reads := 0
+	dataSource, err := inst.NewDataSource("counted", "Counted", &countingProvider{
+		MemoryDataSourceProvider: &MemoryDataSourceProvider{Fixtures: map[string][]*Record{"posts": posts}},
+		reads:                    &reads,
+	}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection := &Collection{Id: "posts", Source: dataSource}
+	q, err := query.ParseFromString(`gt(views 10, {order:["views","desc"], limit:2, group:"category", select:["views"]})`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := collection.FindPage(q, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if reads != 1 {
+		t.Fatalf("Expected the records to be matched once, got %d reads", reads)
+	}
+
+	ids := []string{}
+	for _, record := range page.Records {
+		ids = append(ids, record.Id)
+		if record.Get("category") != nil {
+			t.Errorf("Expected %s to be projected, got %v", record.Id, record.Data)
+		}
+	}
+
+	if diff := deep.Equal(ids, []string{"post-5", "post-4"}); diff != nil {
+		t.Error(diff)
+	} else if page.Total != 4 {
+		t.Errorf("Expected 4 matched records, got %d", page.Total)
+	}
+
+	// aggregations are computed over the matched records before projection
+	expected := map[string][]query.GroupCount{"category": {{Value: "blog", Count: 2}, {Value: "news", Count: 2}}}
+	if diff := deep.Equal(page.Aggregates["group"], expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -1,10 +1,16 @@
 package sulat
 
 import (
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"net/http"
+	"path"
 	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/nedpals/sulatcms/sulat/query"
@@ -12,13 +18,27 @@ import (
 	"golang.org/x/exp/maps"
 )
 
+// DataSourceConfig is the config of a data source stored as JSON
+type DataSourceConfig map[string]any
+
+func (c *DataSourceConfig) Scan(src any) error {
+	return scanJson(src, c, "DataSourceConfig")
+}
+
+func (c DataSourceConfig) Value() (driver.Value, error) {
+	return driverValueJson(c)
+}
+
 type DataSource struct {
 	instance           *Instance
-	Id                 string         `json:"id" db:"id" mapstructure:"id"`
-	Name               string         `json:"name" db:"name" mapstructure:"name"`
-	Config             map[string]any `json:"config" db:"config" mapstructure:"config,omitempty"`
-	ProviderId         string         `json:"provider" db:"provider" mapstructure:"provider"`
+	Id                 string           `json:"id" db:"id" mapstructure:"id"`
+	Name               string           `json:"name" db:"name" mapstructure:"name"`
+	Config             DataSourceConfig `json:"config" db:"config" mapstructure:"config,omitempty"`
+	ProviderId         string           `json:"provider" db:"provider" mapstructure:"provider"`
 	DataSourceProvider `json:"-" db:"-"`
+
+	// initErr is the error returned by the last initialization
+	initErr error
 }
 
 func (ds DataSource) ValidationSchema() Schema {
@@ -45,6 +65,11 @@ func (ds DataSource) ValidationSchema() Schema {
 }
 
 func (ds *DataSource) Initialize() error {
+	ds.initErr = ds.initialize()
+	return ds.initErr
+}
+
+func (ds *DataSource) initialize() error {
 	if ds.DataSourceProvider == nil {
 		provider, err := ds.instance.FindDataSourceProvider(ds.ProviderId)
 		if err != nil {
@@ -54,15 +79,84 @@ func (ds *DataSource) Initialize() error {
 		ds.DataSourceProvider = provider
 	}
 
-	var err error
-	ds.DataSourceProvider, err = ds.DataSourceProvider.WithConfig(ds.Config)
+	provider, err := ds.DataSourceProvider.WithConfig(ds.Config)
 	if err != nil {
 		return err
 	}
 
+	ds.DataSourceProvider = provider
 	return ds.DataSourceProvider.Initialize(ds.instance)
 }
 
+// Reinitialize closes the provider of the data source and initializes a new
+// one with the current config of the data source
+func (ds *DataSource) Reinitialize() error {
+	if err := ds.Close(); err != nil {
+		ds.initErr = err
+		return err
+	}
+
+	// the provider is replaced if the provider id has changed
+	if ds.DataSourceProvider != nil && ds.DataSourceProvider.Properties().Id != ds.ProviderId {
+		ds.DataSourceProvider = nil
+	}
+
+	// the records may have changed so they are indexed again on the
+	// next search
+	if ds.instance != nil {
+		ds.instance.SearchIndex().invalidate("", "")
+	}
+	return ds.Initialize()
+}
+
+// Close releases the resources held by the provider of the data source
+func (ds *DataSource) Close() error {
+	if lifecycle, ok := ds.DataSourceProvider.(DataSourceLifecycle); ok {
+		return lifecycle.Close()
+	}
+	return nil
+}
+
+// Ping checks whether the data source is initialized and its provider can
+// reach its data
+func (ds *DataSource) Ping() error {
+	if ds.DataSourceProvider == nil {
+		return NewResponseError(http.StatusServiceUnavailable, "data source is not initialized")
+	} else if lifecycle, ok := ds.DataSourceProvider.(DataSourceLifecycle); ok {
+		return lifecycle.Ping()
+	}
+	return nil
+}
+
+// Status reports the health of the data source along with the errors
+// encountered while initializing it (e.g. records which failed to import)
+func (ds *DataSource) Status() DataSourceStatus {
+	var status DataSourceStatus
+	if lifecycle, ok := ds.DataSourceProvider.(DataSourceLifecycle); ok {
+		status = lifecycle.Status()
+	} else {
+		status = newDataSourceStatus(ds.Ping(), nil)
+	}
+
+	if ds.initErr != nil {
+		status.Healthy = false
+		status.Errors = append(errorMessages(ds.initErr), status.Errors...)
+	}
+	return status
+}
+
+// errorMessages returns the messages of the errors joined into the error
+func errorMessages(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		messages := []string{}
+		for _, err := range joined.Unwrap() {
+			messages = append(messages, errorMessages(err)...)
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
+
 func fetchDataSources(dataSources *[]*DataSource, db *sqlx.DB) error {
 	return db.Select(dataSources, "SELECT * FROM data_sources")
 }
@@ -78,7 +172,7 @@ func updateDataSource(dataSource *DataSource, db *sqlx.DB) error {
 }
 
 func removeDataSource(dataSourceId string, db *sqlx.DB) error {
-	_, err := db.NamedExec("DELETE FROM data_sources WHERE id = :id", dataSourceId)
+	_, err := db.Exec("DELETE FROM data_sources WHERE id = ?", dataSourceId)
 	return err
 }
 
@@ -93,6 +187,132 @@ type DataSourceProvider interface {
 	Delete(collectionId string, query *query.Query, opts map[string]any) error
 }
 
+// DataSourceCounter is implemented by data source providers which can count
+// the records matching a query without fetching them
+type DataSourceCounter interface {
+	Count(collectionId string, query *query.Query, opts map[string]any) (int, error)
+}
+
+// DataSourceStatus is the health of a data source
+type DataSourceStatus struct {
+	Healthy   bool           `json:"healthy"`
+	Message   string         `json:"message,omitempty"`
+	Errors    []string       `json:"errors,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	CheckedAt time.Time      `json:"checked_at"`
+}
+
+// newDataSourceStatus creates the status of a data source from the result
+// of pinging it
+func newDataSourceStatus(pingErr error, details map[string]any) DataSourceStatus {
+	status := DataSourceStatus{
+		Healthy:   pingErr == nil,
+		Details:   details,
+		CheckedAt: time.Now(),
+	}
+
+	if pingErr != nil {
+		status.Message = pingErr.Error()
+	}
+	return status
+}
+
+// DataSourceLifecycle is implemented by data source providers which hold
+// connections or other resources and can check their health
+type DataSourceLifecycle interface {
+	// Close releases the resources held by the provider
+	Close() error
+
+	// Ping checks whether the provider can reach its data
+	Ping() error
+
+	// Status reports the health of the provider
+	Status() DataSourceStatus
+}
+
+// DataSourceSchemaInferrer is implemented by data source providers which can
+// infer the schema of a collection from its records
+type DataSourceSchemaInferrer interface {
+	InferSchema(collectionId string) (Schema, error)
+}
+
+// DataSourceBatcher is implemented by data source providers which can apply
+// multiple write operations as a single transaction where either all of the
+// operations are applied or none of them
+type DataSourceBatcher interface {
+	Batch(ops []BatchOperation, opts map[string]any) error
+}
+
+// ApplyQueryOptions sorts, paginates and projects the matched records
+// based on the options of the query. Projected records are copies of
+// the matched records.
+func ApplyQueryOptions(q *query.Query, records []*Record) ([]*Record, error) {
+	records, err := query.Apply(q, records)
+	if err != nil {
+		return nil, NewResponseError(http.StatusBadRequest, err.Error())
+	}
+	return ProjectRecords(q, records), nil
+}
+
+// ProjectRecords applies the select option of the query to the records.
+// Providers which sort and paginate the records on their own should use
+// this instead of ApplyQueryOptions.
+func ProjectRecords(q *query.Query, records []*Record) []*Record {
+	opts, _ := q.ParseOptions()
+	if len(opts.Select) == 0 {
+		return records
+	}
+
+	// sort keys are always included so cursors can be created from
+	// the projected records
+	fields := slices.Clone(opts.Select)
+	for _, key := range opts.Order {
+		if !slices.Contains(fields, key.Field) {
+			fields = append(fields, key.Field)
+		}
+	}
+
+	projected := make([]*Record, len(records))
+	for i, record := range records {
+		projected[i] = record.Project(fields)
+	}
+	return projected
+}
+
+// ValidateQuery validates the query and reports the validation
+// errors as a bad request
+func ValidateQuery(q *query.Query) error {
+	err := q.Validate()
+	if validationErrors, ok := err.(query.ValidationErrors); ok {
+		return NewResponseErrorWithDetails(http.StatusBadRequest, "invalid query", validationErrors)
+	} else if err != nil {
+		return NewResponseError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// RecordRevision is a revision of a record kept by a data source
+type RecordRevision struct {
+	Id      string    `json:"id"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
+// DataSourceHistory is implemented by data source providers which keep
+// the revisions of their records
+type DataSourceHistory interface {
+	// History returns the revisions of the record from the newest
+	History(collectionId string, id string, opts map[string]any) ([]*RecordRevision, error)
+
+	// Diff returns the changes made to the record in the revision
+	Diff(collectionId string, id string, revision string, opts map[string]any) (string, error)
+
+	// Restore reverts the record to its state in the revision
+	Restore(collectionId string, id string, revision string, opts map[string]any) (*Record, error)
+}
+
 type DataSourceProviderProperties struct {
 	Id           string
 	Name         string
@@ -103,6 +323,24 @@ type DataSourceProviderProperties struct {
 
 // DATA SOURCE PROVIDER IMPLEMENTATIONS
 
+// Formats of the ids of the records of the file system data source
+const (
+	// FileIdFilename uses the filename of the record (e.g. intro.md)
+	FileIdFilename = "filename"
+
+	// FileIdPath uses the path of the record relative to the root
+	// (e.g. posts/2023/intro.md)
+	FileIdPath = "path"
+
+	// FileIdPathWithoutExt uses the path of the record relative to the
+	// root without its extension (e.g. posts/2023/intro)
+	FileIdPathWithoutExt = "path_without_ext"
+
+	// FileIdFieldPrefix followed by the name of a field uses the value of
+	// the field (e.g. field:slug)
+	FileIdFieldPrefix = "field:"
+)
+
 // FileDataSourceProvider is a data source provider that uses the file system as its data source
 type FileDataSourceProvider struct {
 	// FS is the file system to use for this provider
@@ -117,14 +355,66 @@ type FileDataSourceProvider struct {
 	// Root is the root directory of the data source
 	Root string
 
-	// Collections is a map of collection ids to globbed paths
+	// Collections is a map of collection ids to globbed paths. Globs
+	// support ** for matching any number of directories.
 	Collections map[string]string
 
+	// Includes is a map of collection ids to additional globbed paths
+	// of the records of the collections
+	Includes map[string][]string
+
+	// Excludes is a map of collection ids to the globbed paths excluded
+	// from the collections
+	Excludes map[string][]string
+
+	// IgnoreFiles are the .gitignore-style files in the root listing the
+	// paths excluded from all collections. Defaults to .gitignore and
+	// .sulatignore.
+	IgnoreFiles []string
+
+	// Tables is a map of collection ids to the paths of CSV or JSON array
+	// files where each row is a record of the collection
+	Tables map[string]string
+
+	// TableIdColumns is a map of collection ids to the columns of their
+	// tables containing the record ids. Defaults to "id".
+	TableIdColumns map[string]string
+
+	// IdFormats is a map of collection ids to the formats of the ids of
+	// their records. Defaults to FileIdFilename.
+	IdFormats map[string]string
+
+	// PathTemplates is a map of collection ids to the path templates of
+	// their new records (e.g. posts/{{date:2006}}/{{slug}}.md). Records of
+	// collections without a path template are stored in the directory of
+	// the collection glob.
+	PathTemplates map[string]string
+
+	// SlugFields is a map of collection ids to the fields used for
+	// generating the slugs of their records. Defaults to "title".
+	SlugFields map[string]string
+
+	// Fsync flushes the written files to the disk before they replace
+	// the existing files
+	Fsync bool
+
 	// cachedCollections is a map of collection ids to collections
 	cachedCollections map[string]*Collection
 
 	// records is a map of collection ids to records
 	records map[string]map[string]*Record
+
+	// tables is a map of collection ids to the tables of tabular collections
+	tables map[string]*tabularFile
+
+	// ignores are the rules read from the ignore files
+	ignores ignoreList
+
+	// journal keeps the files changed by the running batch
+	journal *fileJournal
+
+	// mu guards records from concurrent reads and writes
+	mu sync.RWMutex
 }
 
 func injectConfigToProvider(p *FileDataSourceProvider, config map[string]any) {
@@ -154,14 +444,105 @@ func injectConfigToProvider(p *FileDataSourceProvider, config map[string]any) {
 		}
 	}
 
+	for key, dest := range map[string]*map[string]string{
+		"tables":           &p.Tables,
+		"table_id_columns": &p.TableIdColumns,
+		"id_formats":       &p.IdFormats,
+		"paths":            &p.PathTemplates,
+		"slug_fields":      &p.SlugFields,
+	} {
+		rawValues, ok := config[key]
+		if !ok {
+			continue
+		} else if *dest == nil {
+			*dest = make(map[string]string)
+		}
+
+		if values, ok := rawValues.(map[string]string); ok {
+			maps.Copy(*dest, values)
+		} else if values, ok := rawValues.(map[string]any); ok {
+			for collectionId, value := range values {
+				if value, ok := value.(string); ok {
+					(*dest)[collectionId] = value
+				}
+			}
+		}
+	}
+
+	for key, dest := range map[string]*map[string][]string{
+		"includes": &p.Includes,
+		"excludes": &p.Excludes,
+	} {
+		rawValues, ok := config[key]
+		if !ok {
+			continue
+		} else if *dest == nil {
+			*dest = make(map[string][]string)
+		}
+
+		if values, ok := rawValues.(map[string][]string); ok {
+			maps.Copy(*dest, values)
+		} else if values, ok := rawValues.(map[string]any); ok {
+			for collectionId, value := range values {
+				(*dest)[collectionId] = castStringList(value)
+			}
+		}
+	}
+
+	if rawIgnoreFiles, ok := config["ignore_files"]; ok {
+		p.IgnoreFiles = castStringList(rawIgnoreFiles)
+	}
+
 	if rawRoot, ok := config["root"]; ok {
 		if root, ok := rawRoot.(string); ok {
 			p.Root = root
 		}
 	}
+
+	if rawFsync, ok := config["fsync"]; ok {
+		if fsync, ok := rawFsync.(bool); ok {
+			p.Fsync = fsync
+		}
+	}
+}
+
+// castStringList converts a string or a list of strings into a list of strings
+func castStringList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return slices.Clone(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if item, ok := item.(string); ok {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return []string{}
+}
+
+// matcher returns the matcher of the paths of the records of the collection
+func (p *FileDataSourceProvider) matcher(collectionId string) pathMatcher {
+	matcher := pathMatcher{ignores: p.ignores}
+	for _, pattern := range append([]string{p.Collections[collectionId]}, p.Includes[collectionId]...) {
+		if len(pattern) != 0 {
+			matcher.includes = append(matcher.includes, path.Clean(filepath.ToSlash(pattern)))
+		}
+	}
+
+	for _, pattern := range p.Excludes[collectionId] {
+		matcher.excludes = append(matcher.excludes, path.Clean(filepath.ToSlash(pattern)))
+	}
+	return matcher
 }
 
 func (p *FileDataSourceProvider) Initialize(i *Instance) error {
+	// the records may be read and written while they are reloaded
+	p.mu.Lock()
 	if !slices.Equal(p.codecs, i.Codecs()) {
 		p.codecs = i.Codecs()
 	}
@@ -173,78 +554,125 @@ func (p *FileDataSourceProvider) Initialize(i *Instance) error {
 	if p.cachedCollections == nil {
 		p.cachedCollections = make(map[string]*Collection)
 	}
+	p.mu.Unlock()
+
+	ignoreFiles := p.IgnoreFiles
+	if ignoreFiles == nil {
+		ignoreFiles = defaultIgnoreFiles
+	}
+
+	ignores, err := loadIgnoreFiles(p.FS, p.Root, ignoreFiles)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.ignores = ignores
+	p.mu.Unlock()
+
+	for collectionId, format := range p.IdFormats {
+		if !isValidFileIdFormat(format) {
+			return fmt.Errorf("collection %s: unknown id format %q", collectionId, format)
+		}
+	}
 
 	var importErrors []error
 
-	for collectionId, glob := range p.Collections {
+	for collectionId := range p.Collections {
 		// create collection first
 		collection := &Collection{
 			Id: collectionId,
 		}
 
-		// TODO: replace this and make it "importable" to site instead
-		p.cachedCollections[collectionId] = collection
-
 		// import records
-		files, err := afero.Glob(p.FS, filepath.Join(p.Root, glob))
+		files, err := p.matcher(collectionId).glob(p.FS, p.Root)
 		if err != nil {
 			return err
 		}
 
-		if len(files) == 0 {
-			continue
-		}
-
 		records := map[string]*Record{}
+		filenamesById := map[string]string{}
 		for _, filename := range files {
-			file, err := p.FS.Open(filename)
-			if err != nil {
-				importErrors = append(importErrors, err)
-				continue
-			}
-
-			stat, err := file.Stat()
+			record, err := p.readRecordFile(filename)
 			if err != nil {
 				importErrors = append(importErrors, err)
 				continue
 			}
 
-			codec, err := i.Codecs().FindByFileName(stat.Name())
+			id, err := p.recordId(collectionId, filename, record)
 			if err != nil {
-				importErrors = append(importErrors, err)
+				importErrors = append(importErrors, fmt.Errorf("%s: %w", filename, err))
 				continue
-			}
-
-			record, err := codec.Deserialize(stat.Name(), file)
-			if err != nil {
-				importErrors = append(importErrors, err)
+			} else if existingFilename, isDuplicate := filenamesById[id]; isDuplicate {
+				importErrors = append(importErrors, fmt.Errorf("collection %s: %s and %s have the same record id %q", collectionId, existingFilename, filename, id))
 				continue
 			}
+			filenamesById[id] = filename
 
-			// strip root path from filename
-			finalFilename := filename
-			relFilename, err := filepath.Rel(p.Root, filename)
-			if err == nil {
-				finalFilename = relFilename
-			}
-
-			records[finalFilename] = &Record{
-				Id:         record.Id,
+			records[filename] = &Record{
+				Id:         id,
 				Collection: collection,
 				Data:       record.Data,
 			}
 		}
 
+		p.mu.Lock()
+		// TODO: replace this and make it "importable" to site instead
+		p.cachedCollections[collectionId] = collection
+
 		if p.records == nil {
 			p.records = make(map[string]map[string]*Record)
 		}
 
 		p.records[collectionId] = records
+		p.mu.Unlock()
+	}
+
+	for collectionId, tablePath := range p.Tables {
+		collection := &Collection{
+			Id: collectionId,
+		}
+
+		p.mu.Lock()
+		p.cachedCollections[collectionId] = collection
+		records, err := p.importTable(collection, tablePath)
+		if err == nil {
+			if p.records == nil {
+				p.records = make(map[string]map[string]*Record)
+			}
+			p.records[collectionId] = records
+		}
+		p.mu.Unlock()
+
+		if err != nil {
+			importErrors = append(importErrors, err)
+		}
 	}
 
 	return errors.Join(importErrors...)
 }
 
+// readRecordFile decodes the file relative to the root with the codec
+// matching its name
+func (p *FileDataSourceProvider) readRecordFile(filename string) (*Record, error) {
+	file, err := p.FS.Open(filepath.Join(p.Root, filename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := p.codecs.FindByFileName(stat.Name())
+	if err != nil {
+		return nil, err
+	}
+	return codec.Deserialize(stat.Name(), file)
+}
+
 func (p *FileDataSourceProvider) Properties() DataSourceProviderProperties {
 	return DataSourceProviderProperties{
 		Id:      "fs",
@@ -263,6 +691,26 @@ func (p *FileDataSourceProvider) Properties() DataSourceProviderProperties {
 					FieldLabel: "Root",
 				},
 			},
+			RepeaterSchemaField{
+				BaseField: BaseField{
+					FieldName:  "ignore_files",
+					FieldLabel: "Ignore files",
+				},
+				BaseSchemaField: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "path",
+						FieldLabel: "Ignore file path",
+						Required:   true,
+					},
+				},
+				MaxLength: 16,
+			},
+			BooleanSchemaField{
+				BaseField: BaseField{
+					FieldName:  "fsync",
+					FieldLabel: "Flush writes to disk",
+				},
+			},
 			KVGroupSchemaField{
 				BaseField: BaseField{
 					FieldName:  "collections",
@@ -283,6 +731,160 @@ func (p *FileDataSourceProvider) Properties() DataSourceProviderProperties {
 					},
 				},
 			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "includes",
+					FieldLabel: "Included paths",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: RepeaterSchemaField{
+					BaseField: BaseField{
+						FieldName:  "paths",
+						FieldLabel: "Included paths",
+					},
+					BaseSchemaField: StringSchemaField{
+						BaseField: BaseField{
+							FieldName:  "path",
+							FieldLabel: "Included path",
+							Required:   true,
+						},
+					},
+					MinLength: 1,
+					MaxLength: 64,
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "excludes",
+					FieldLabel: "Excluded paths",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: RepeaterSchemaField{
+					BaseField: BaseField{
+						FieldName:  "paths",
+						FieldLabel: "Excluded paths",
+					},
+					BaseSchemaField: StringSchemaField{
+						BaseField: BaseField{
+							FieldName:  "path",
+							FieldLabel: "Excluded path",
+							Required:   true,
+						},
+					},
+					MinLength: 1,
+					MaxLength: 64,
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "tables",
+					FieldLabel: "Tables",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "path",
+						FieldLabel: "Table path",
+						Required:   true,
+					},
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "table_id_columns",
+					FieldLabel: "Table ID columns",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "column",
+						FieldLabel: "ID column",
+					},
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "id_formats",
+					FieldLabel: "ID formats",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "format",
+						FieldLabel: "ID format",
+						Required:   true,
+					},
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "paths",
+					FieldLabel: "Paths",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "path",
+						FieldLabel: "Path template",
+						Required:   true,
+					},
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "slug_fields",
+					FieldLabel: "Slug fields",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "field",
+						FieldLabel: "Slug field",
+					},
+				},
+			},
 		},
 	}
 }
@@ -294,7 +896,7 @@ func (p *FileDataSourceProvider) WithConfig(config map[string]any) (DataSourcePr
 
 	injectConfigToProvider(newProvider, config)
 
-	if len(newProvider.Root) != 0 && len(newProvider.Collections) != 0 {
+	if len(newProvider.Root) != 0 && (len(newProvider.Collections) != 0 || len(newProvider.Tables) != 0) {
 		return newProvider, nil
 	}
 
@@ -302,14 +904,18 @@ func (p *FileDataSourceProvider) WithConfig(config map[string]any) (DataSourcePr
 
 	// get nearest config file if no config path is specified
 	if len(newProvider.ConfigPath) == 0 && len(newProvider.Root) != 0 {
-		configFilePaths, err := afero.Glob(newProvider.FS, filepath.Join(newProvider.Root, "**", "sulat.toml"))
+		configFilePaths, err := pathMatcher{includes: []string{"**/sulat.toml"}}.glob(newProvider.FS, newProvider.Root)
 		if err != nil {
 			return nil, err
 		} else if len(configFilePaths) == 0 {
 			return nil, errors.New("no sulat.toml found")
 		}
 
-		newProvider.ConfigPath = configFilePaths[0]
+		// the config file closest to the root is used
+		slices.SortStableFunc(configFilePaths, func(a, b string) int {
+			return strings.Count(a, string(filepath.Separator)) - strings.Count(b, string(filepath.Separator))
+		})
+		newProvider.ConfigPath = filepath.Join(newProvider.Root, configFilePaths[0])
 	}
 
 	// still if there's no config path, return an error
@@ -357,7 +963,7 @@ func (p *FileDataSourceProvider) saveRecord(filename string, record *Record) err
 		return err
 	}
 
-	if err := afero.WriteFile(p.FS, filepath.Join(p.Root, filename), data, 0644); err != nil {
+	if err := p.writeFile(filename, data); err != nil {
 		return err
 	}
 
@@ -365,7 +971,128 @@ func (p *FileDataSourceProvider) saveRecord(filename string, record *Record) err
 	return nil
 }
 
+// newRecordFilename returns the filename of the record relative to the root
+// based on the path template of its collection
+func (p *FileDataSourceProvider) newRecordFilename(collectionId string, record *Record) (string, error) {
+	var filename string
+	if template, ok := p.PathTemplates[collectionId]; ok && len(template) != 0 {
+		renderedFilename, err := PathTemplate{
+			Template:  template,
+			SlugField: p.SlugFields[collectionId],
+		}.Render(record)
+		if err != nil {
+			return "", err
+		}
+		filename = renderedFilename
+	} else {
+		defaultFilename, err := p.defaultRecordFilename(collectionId, record)
+		if err != nil {
+			return "", err
+		}
+		filename = defaultFilename
+	}
+
+	// records outside the collection glob will not be imported back
+	if !p.matcher(collectionId).match(filepath.ToSlash(filename)) {
+		return "", NewResponseError(http.StatusBadRequest, "path of the record does not match the collection path")
+	}
+	return filename, nil
+}
+
+// defaultRecordFilename returns the filename of the record relative to the
+// root for collections without path templates based on the id format of
+// the collection. Records are stored in the directory of the collection
+// glob unless the id contains the path of the record.
+func (p *FileDataSourceProvider) defaultRecordFilename(collectionId string, record *Record) (string, error) {
+	glob := p.Collections[collectionId]
+	format := p.IdFormats[collectionId]
+
+	if strings.HasPrefix(format, FileIdFieldPrefix) {
+		id, err := p.recordId(collectionId, "", record)
+		if err != nil {
+			return "", err
+		}
+		return p.withGlobExt(glob, filepath.Join(globDir(glob), Slugify(id)))
+	} else if len(record.Id) == 0 {
+		return "", NewResponseError(http.StatusBadRequest, "record id is required")
+	}
+
+	switch format {
+	case FileIdPath:
+		return filepath.Clean(filepath.FromSlash(record.Id)), nil
+	case FileIdPathWithoutExt:
+		return p.withGlobExt(glob, filepath.Clean(filepath.FromSlash(record.Id)))
+	default:
+		return filepath.Join(globDir(glob), record.Id), nil
+	}
+}
+
+// withGlobExt appends the file extension of the glob to the filename
+func (p *FileDataSourceProvider) withGlobExt(glob string, filename string) (string, error) {
+	ext := path.Ext(filepath.ToSlash(glob))
+	if len(ext) <= 1 || strings.ContainsAny(ext, `*?[{\`) {
+		return "", NewResponseError(http.StatusBadRequest, "file extension of the record cannot be determined from the collection path")
+	}
+	return filename + ext, nil
+}
+
+// isValidFileIdFormat reports whether the id format is supported
+func isValidFileIdFormat(format string) bool {
+	switch format {
+	case "", FileIdFilename, FileIdPath, FileIdPathWithoutExt:
+		return true
+	}
+	return strings.HasPrefix(format, FileIdFieldPrefix) && len(format) > len(FileIdFieldPrefix)
+}
+
+// recordId returns the id of the record stored in the file based on the id
+// format of its collection
+func (p *FileDataSourceProvider) recordId(collectionId string, filename string, record *Record) (string, error) {
+	format := p.IdFormats[collectionId]
+	switch {
+	case format == FileIdPath:
+		return filepath.ToSlash(filename), nil
+	case format == FileIdPathWithoutExt:
+		return strings.TrimSuffix(filepath.ToSlash(filename), filepath.Ext(filename)), nil
+	case strings.HasPrefix(format, FileIdFieldPrefix):
+		field := strings.TrimPrefix(format, FileIdFieldPrefix)
+		value := record.Get(field)
+		if value == nil || len(fmt.Sprint(value)) == 0 {
+			return "", NewResponseError(http.StatusBadRequest, fmt.Sprintf("field %s is required for the id of the record", field))
+		}
+		return fmt.Sprint(value), nil
+	default:
+		return filepath.Base(filename), nil
+	}
+}
+
+// matchFilenames returns the filenames and ids of the records matching
+// the query sorted by filename
+func (p *FileDataSourceProvider) matchFilenames(collectionId string, q *query.Query) ([]string, []string, error) {
+	records, collectionFound := p.records[collectionId]
+	if !collectionFound {
+		return nil, nil, NewResponseError(http.StatusNotFound, "collection not found")
+	}
+
+	filenames := []string{}
+	for filename, record := range records {
+		if q == nil || q.Match(record) {
+			filenames = append(filenames, filename)
+		}
+	}
+	slices.Sort(filenames)
+
+	ids := make([]string, len(filenames))
+	for i, filename := range filenames {
+		ids[i] = records[filename].Id
+	}
+	return filenames, ids, nil
+}
+
 func (p *FileDataSourceProvider) Get(collectionId string, id string, opts map[string]any) (*Record, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	_, record, err := p.fetchRecord(collectionId, id)
 	if err != nil {
 		return nil, err
@@ -373,39 +1100,102 @@ func (p *FileDataSourceProvider) Get(collectionId string, id string, opts map[st
 	return record, nil
 }
 
-func (p *FileDataSourceProvider) Find(collectionId string, query *query.Query, opts map[string]any) ([]*Record, error) {
+func (p *FileDataSourceProvider) matchRecords(collectionId string, q *query.Query) ([]*Record, error) {
 	records, collectionFound := p.records[collectionId]
 	if !collectionFound {
 		return nil, NewResponseError(http.StatusNotFound, "collection not found")
-	}
-
-	if query == nil {
-		return maps.Values(records), nil
+	} else if err := ValidateQuery(q); err != nil {
+		return nil, err
 	}
 
 	// match query against the records
 	found := []*Record{}
 
 	for _, record := range records {
-		if query.Match(record) {
+		if q == nil || q.Match(record) {
 			found = append(found, record)
 		}
 	}
 
-	if len(found) == 0 {
+	return found, nil
+}
+
+func (p *FileDataSourceProvider) Find(collectionId string, q *query.Query, opts map[string]any) ([]*Record, error) {
+	p.mu.RLock()
+	found, err := p.matchRecords(collectionId, q)
+	p.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if q != nil && len(found) == 0 {
 		return nil, NewResponseError(http.StatusNotFound, "no records found")
 	}
 
-	return found, nil
+	return ApplyQueryOptions(q, found)
 }
 
-func (p *FileDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
-	// check for duplicate id
-	_, existingRecord, _ := p.fetchRecord(collectionId, record.Id)
-	if existingRecord != nil {
-		return NewResponseError(http.StatusConflict, "record already exists")
+func (p *FileDataSourceProvider) Count(collectionId string, q *query.Query, opts map[string]any) (int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	found, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return 0, err
+	}
+	return len(found), nil
+}
+
+// Ping checks whether the root of the data source is an accessible directory
+func (p *FileDataSourceProvider) Ping() error {
+	root := p.Root
+	if len(root) == 0 {
+		root = "."
+	}
+
+	info, err := p.FS.Stat(root)
+	if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
 	}
+	return nil
+}
 
+// Close releases the records kept in memory
+func (p *FileDataSourceProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records = nil
+	p.tables = nil
+	return nil
+}
+
+// Status reports the health of the data source and the number of records
+// of its collections
+func (p *FileDataSourceProvider) Status() DataSourceStatus {
+	p.mu.RLock()
+	counts := make(map[string]any, len(p.records))
+	for collectionId, records := range p.records {
+		counts[collectionId] = len(records)
+	}
+	p.mu.RUnlock()
+
+	return newDataSourceStatus(p.Ping(), map[string]any{
+		"root":    p.Root,
+		"records": counts,
+	})
+}
+
+func (p *FileDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.insert(collectionId, record)
+}
+
+// insert saves the new record. The lock must be held by the caller.
+func (p *FileDataSourceProvider) insert(collectionId string, record *Record) error {
 	if record.Collection == nil {
 		collection, found := p.cachedCollections[collectionId]
 		if !found {
@@ -415,8 +1205,34 @@ func (p *FileDataSourceProvider) Insert(collectionId string, record *Record, opt
 		record.Collection = collection
 	}
 
+	if _, isTable := p.tables[collectionId]; isTable {
+		if len(record.Id) == 0 {
+			return NewResponseError(http.StatusBadRequest, "record id is required")
+		} else if _, existingRecord, _ := p.fetchRecord(collectionId, record.Id); existingRecord != nil {
+			return NewResponseError(http.StatusConflict, "record already exists")
+		}
+		return p.putTableRow(collectionId, record)
+	}
+
+	filename, err := p.newRecordFilename(collectionId, record)
+	if err != nil {
+		return err
+	}
+
+	id, err := p.recordId(collectionId, filename, record)
+	if err != nil {
+		return err
+	}
+	record.Id = id
+
+	// check for duplicate id
+	_, existingRecord, _ := p.fetchRecord(collectionId, record.Id)
+	if _, exists := p.records[collectionId][filename]; exists || existingRecord != nil {
+		return NewResponseError(http.StatusConflict, "record already exists")
+	}
+
 	if record.Codec == nil {
-		codec, err := p.codecs.FindByFileName(record.Id)
+		codec, err := p.codecs.FindByFileName(filename)
 		if err != nil {
 			return err
 		}
@@ -425,14 +1241,26 @@ func (p *FileDataSourceProvider) Insert(collectionId string, record *Record, opt
 	}
 
 	// save to fs
-	filename := filepath.Join(collectionId, record.Id)
 	return p.saveRecord(filename, record)
 }
 
 func (p *FileDataSourceProvider) Update(collectionId string, updateRecord *Record, opts map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.update(collectionId, updateRecord)
+}
+
+// update replaces the data of the record. The lock must be held by the caller.
+func (p *FileDataSourceProvider) update(collectionId string, updateRecord *Record) error {
 	filename, record, err := p.fetchRecord(collectionId, updateRecord.Id)
 	if err != nil {
 		return err
+	} else if _, isTable := p.tables[collectionId]; isTable {
+		return p.putTableRow(collectionId, &Record{
+			Id:         record.Id,
+			Collection: record.Collection,
+			Data:       updateRecord.Data,
+		})
 	}
 
 	// merge record with updateRecord
@@ -443,25 +1271,96 @@ func (p *FileDataSourceProvider) Update(collectionId string, updateRecord *Recor
 		Codec:      record.Codec,
 	}
 
-	return p.saveRecord(filename, updatedRecord)
+	// imported records do not have a codec attached
+	if updatedRecord.Codec == nil {
+		updatedRecord.Codec = updateRecord.Codec
+	}
+
+	if updatedRecord.Codec == nil {
+		codec, err := p.codecs.FindByFileName(filename)
+		if err != nil {
+			return err
+		}
+		updatedRecord.Codec = codec
+	}
+
+	// move the record if the fields used in its path have changed
+	newFilename := filename
+	if _, hasTemplate := p.PathTemplates[collectionId]; hasTemplate {
+		newFilename, err = p.newRecordFilename(collectionId, updatedRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ids taken from fields change along with the fields
+	newId, err := p.recordId(collectionId, newFilename, updatedRecord)
+	if err != nil {
+		return err
+	} else if newFilename == filename && newId == record.Id {
+		return p.saveRecord(filename, updatedRecord)
+	}
+
+	_, idOwner, _ := p.fetchRecord(collectionId, newId)
+	pathOwner := p.records[collectionId][newFilename]
+	if (idOwner != nil && idOwner != record) || (pathOwner != nil && pathOwner != record) {
+		return NewResponseError(http.StatusConflict, "record already exists")
+	}
+
+	updatedRecord.Id = newId
+	if err := p.saveRecord(newFilename, updatedRecord); err != nil {
+		return err
+	} else if newFilename != filename {
+		if err := p.removeFile(filename); err != nil {
+			return err
+		}
+		delete(p.records[collectionId], filename)
+	}
+
+	updateRecord.Id = newId
+	return nil
 }
 
 func (p *FileDataSourceProvider) Delete(collectionId string, query *query.Query, opts map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.delete(collectionId, query)
+}
+
+// delete removes the records matching the query. The lock must be held by
+// the caller.
+func (p *FileDataSourceProvider) delete(collectionId string, query *query.Query) error {
 	records, collectionFound := p.records[collectionId]
 	if !collectionFound {
 		return NewResponseError(http.StatusNotFound, "collection not found")
 	} else if query == nil {
 		return NewResponseError(http.StatusBadRequest, "query is required")
+	} else if err := ValidateQuery(query); err != nil {
+		return err
 	}
 
-	// match query against the records
-	maps.DeleteFunc(records, func(k string, r *Record) bool {
-		isMatched := query.Match(r)
-		if isMatched {
-			p.FS.Remove(k)
+	if _, isTable := p.tables[collectionId]; isTable {
+		ids := []string{}
+		for id, record := range records {
+			if query.Match(record) {
+				ids = append(ids, id)
+			}
 		}
-		return isMatched
-	})
+		return p.deleteTableRows(collectionId, ids)
+	}
+
+	filenames, _, err := p.matchFilenames(collectionId, query)
+	if err != nil {
+		return err
+	}
+
+	// records are only removed once their files have been removed
+	for _, filename := range filenames {
+		if err := p.removeFile(filename); err != nil {
+			return err
+		}
+		delete(records, filename)
+	}
 
 	return nil
 }
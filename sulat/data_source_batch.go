@@ -0,0 +1,290 @@
+package sulat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+	"golang.org/x/exp/maps"
+)
+
+// BatchAction is the write operation performed by a batch operation
+type BatchAction string
+
+const (
+	BatchInsert BatchAction = "insert"
+	BatchUpdate BatchAction = "update"
+	BatchDelete BatchAction = "delete"
+)
+
+// BatchOperation is a write operation applied as part of a batch. Insert
+// and update operations use the record while delete operations use the
+// query.
+type BatchOperation struct {
+	Action       BatchAction  `json:"action"`
+	CollectionId string       `json:"collection"`
+	Record       *Record      `json:"record,omitempty"`
+	Query        *query.Query `json:"query,omitempty"`
+}
+
+// writeFileAtomic writes the data to a temporary file in the directory of
+// the file and renames it to the file once written so readers never see
+// a partially written file
+func writeFileAtomic(fs afero.Fs, filename string, data []byte, sync bool) error {
+	dir := filepath.Dir(filename)
+	if err := fs.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := afero.TempFile(fs, dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	tempName := file.Name()
+	_, err = file.Write(data)
+	if err == nil && sync {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fs.Chmod(tempName, 0644)
+	}
+	if err == nil {
+		err = fs.Rename(tempName, filename)
+	}
+
+	if err != nil {
+		fs.Remove(tempName)
+		return err
+	}
+
+	// the rename is only durable once the directory is synced
+	if sync {
+		return syncDir(fs, dir)
+	}
+	return nil
+}
+
+// syncDir flushes the entries of the directory to the disk. Directories
+// cannot be opened for syncing on Windows so they are skipped there.
+func syncDir(fs afero.Fs, dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	file, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = file.Sync()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// fileJournal keeps the original contents of the files changed during a
+// batch so the changes can be rolled back
+type fileJournal struct {
+	// filenames are the changed files in the order they were first changed
+	filenames []string
+
+	// contents are the original contents of the files. Files which did
+	// not exist before the batch have nil contents.
+	contents map[string][]byte
+}
+
+// track saves the original contents of the file if it was not changed
+// earlier in the batch
+func (j *fileJournal) track(fs afero.Fs, filename string) error {
+	if _, tracked := j.contents[filename]; tracked {
+		return nil
+	}
+
+	content, err := afero.ReadFile(fs, filename)
+	if errors.Is(err, os.ErrNotExist) {
+		content = nil
+	} else if err != nil {
+		return err
+	} else if content == nil {
+		content = []byte{}
+	}
+
+	j.filenames = append(j.filenames, filename)
+	j.contents[filename] = content
+	return nil
+}
+
+// rollback restores the changed files to their original contents
+func (j *fileJournal) rollback(fs afero.Fs, sync bool) error {
+	var rollbackErrors []error
+	for i := len(j.filenames) - 1; i >= 0; i-- {
+		filename := j.filenames[i]
+		content := j.contents[filename]
+
+		var err error
+		if content == nil {
+			err = fs.Remove(filename)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = writeFileAtomic(fs, filename, content, sync)
+		}
+
+		if err != nil {
+			rollbackErrors = append(rollbackErrors, err)
+		}
+	}
+	return errors.Join(rollbackErrors...)
+}
+
+// writeFile atomically writes the data to the file relative to the root
+func (p *FileDataSourceProvider) writeFile(filename string, data []byte) error {
+	fullPath := filepath.Join(p.Root, filename)
+	if p.journal != nil {
+		if err := p.journal.track(p.FS, fullPath); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(p.FS, fullPath, data, p.Fsync)
+}
+
+// removeFile removes the file relative to the root. Files which no longer
+// exist are ignored.
+func (p *FileDataSourceProvider) removeFile(filename string) error {
+	fullPath := filepath.Join(p.Root, filename)
+	if p.journal != nil {
+		if err := p.journal.track(p.FS, fullPath); err != nil {
+			return err
+		}
+	}
+
+	if err := p.FS.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Batch applies the operations as a single transaction. If any of the
+// operations fails, the changes made by the previous operations are
+// rolled back.
+func (p *FileDataSourceProvider) Batch(ops []BatchOperation, opts map[string]any) error {
+	_, _, err := p.batch(ops)
+	return err
+}
+
+// batch applies the operations and returns the paths of the changed files
+// relative to the root and the ids of the changed records
+func (p *FileDataSourceProvider) batch(ops []BatchOperation) ([]string, []string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// records and tables are replaced by the operations instead of being
+	// modified so shallow copies are enough to restore them
+	records := make(map[string]map[string]*Record, len(p.records))
+	for collectionId, collectionRecords := range p.records {
+		records[collectionId] = maps.Clone(collectionRecords)
+	}
+
+	tables := make(map[string]tabularFile, len(p.tables))
+	for collectionId, table := range p.tables {
+		tableCopy := *table
+		tableCopy.Columns = slices.Clone(table.Columns)
+		tableCopy.Ids = slices.Clone(table.Ids)
+		tables[collectionId] = tableCopy
+	}
+
+	p.journal = &fileJournal{contents: map[string][]byte{}}
+	defer func() {
+		p.journal = nil
+	}()
+
+	changedIds := []string{}
+	for i, op := range ops {
+		ids, err := p.applyBatchOperation(op)
+		if err != nil {
+			rollbackErr := p.journal.rollback(p.FS, p.Fsync)
+
+			p.records = records
+			for collectionId, table := range tables {
+				*p.tables[collectionId] = table
+			}
+
+			if rErr, ok := err.(*ResponseError); ok {
+				err = NewResponseErrorWithDetails(rErr.StatusCode, fmt.Sprintf("operation %d: %s", i, rErr.Message), rErr.Details)
+			} else {
+				err = fmt.Errorf("operation %d: %w", i, err)
+			}
+			if rollbackErr != nil {
+				return nil, nil, errors.Join(err, rollbackErr)
+			}
+			return nil, nil, err
+		}
+		changedIds = append(changedIds, ids...)
+	}
+
+	changed := make([]string, len(p.journal.filenames))
+	for i, filename := range p.journal.filenames {
+		if relFilename, err := filepath.Rel(p.Root, filename); err == nil {
+			filename = relFilename
+		}
+		changed[i] = filename
+	}
+	return changed, changedIds, nil
+}
+
+// applyBatchOperation applies the operation of a batch and returns the ids
+// of the changed records. The lock must be held by the caller.
+func (p *FileDataSourceProvider) applyBatchOperation(op BatchOperation) ([]string, error) {
+	switch op.Action {
+	case BatchInsert, BatchUpdate:
+		if op.Record == nil {
+			return nil, NewResponseError(http.StatusBadRequest, "record is required")
+		}
+
+		var err error
+		if op.Action == BatchInsert {
+			err = p.insert(op.CollectionId, op.Record)
+		} else {
+			err = p.update(op.CollectionId, op.Record)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []string{op.Record.Id}, nil
+	case BatchDelete:
+		var ids []string
+		if op.Query != nil {
+			ids, _ = p.matchIds(op.CollectionId, op.Query)
+		}
+		return ids, p.delete(op.CollectionId, op.Query)
+	default:
+		return nil, NewResponseError(http.StatusBadRequest, fmt.Sprintf("unknown batch action: %s", op.Action))
+	}
+}
+
+// matchIds returns the ids of the records matching the query
+func (p *FileDataSourceProvider) matchIds(collectionId string, q *query.Query) ([]string, error) {
+	records, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.Id
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
@@ -0,0 +1,125 @@
+package sulat
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+)
+
+func TestFileDataSourceProviderWrites(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "site/data/a.json", []byte(`{"name":"A"}`), 0644)
+	afero.WriteFile(fs, "site/data/b.json", []byte(`{"name":"B"}`), 0644)
+	afero.WriteFile(fs, "site/data/prices.csv", []byte("id,price\napple,1\n"), 0644)
+
+	dataSource, err := inst.NewDataSource("site", "Site", &FileDataSourceProvider{FS: fs}, map[string]any{
+		"root":        "site",
+		"fsync":       true,
+		"collections": map[string]any{"data": "data/*.json"},
+		"tables":      map[string]any{"prices": "data/prices.csv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := dataSource.DataSourceProvider.(*FileDataSourceProvider)
+
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+		content, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	t.Run("Atomic writes", func(t *testing.T) {
+		if err := dataSource.Update("data", &Record{Id: "a.json", Data: map[string]any{"name": "Alpha"}}, nil); err != nil {
+			t.Fatal(err)
+		} else if content := readFile(t, "site/data/a.json"); content != `{"name":"Alpha"}` {
+			t.Fatalf("Unexpected file: %s", content)
+		}
+
+		// temporary files are renamed to the record files
+		files, err := afero.Glob(fs, "site/data/.*")
+		if err != nil {
+			t.Fatal(err)
+		} else if len(files) != 0 {
+			t.Fatalf("Expected no temporary files, got %v", files)
+		}
+
+		// the directory is synced after the rename on disk
+		dir := t.TempDir()
+		if err := writeFileAtomic(afero.NewOsFs(), filepath.Join(dir, "nested", "a.json"), []byte(`{}`), true); err != nil {
+			t.Fatal(err)
+		} else if content, err := os.ReadFile(filepath.Join(dir, "nested", "a.json")); err != nil || string(content) != `{}` {
+			t.Fatalf("Unexpected file: %q (%v)", content, err)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		err := provider.Batch([]BatchOperation{
+			{Action: BatchInsert, CollectionId: "data", Record: &Record{Id: "c.json", Data: map[string]any{"name": "C"}}},
+			{Action: BatchUpdate, CollectionId: "data", Record: &Record{Id: "a.json", Data: map[string]any{"name": "A2"}}},
+			{Action: BatchDelete, CollectionId: "data", Query: query.Eq("id", "b.json")},
+			{Action: BatchInsert, CollectionId: "prices", Record: &Record{Id: "banana", Data: map[string]any{"price": 2}}},
+			{Action: BatchInsert, CollectionId: "data", Record: &Record{Id: "c.json", Data: map[string]any{"name": "Duplicate"}}},
+		}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusConflict || rErr.Message != "operation 4: record already exists" {
+			t.Fatalf("Expected conflict error on the last operation, got %v", err)
+		}
+
+		// the changes of the failed batch are rolled back
+		if exists, _ := afero.Exists(fs, "site/data/c.json"); exists {
+			t.Fatal("Expected the inserted file to be removed")
+		} else if content := readFile(t, "site/data/a.json"); content != `{"name":"Alpha"}` {
+			t.Fatalf("Expected the updated file to be restored, got %s", content)
+		} else if content := readFile(t, "site/data/b.json"); content != `{"name":"B"}` {
+			t.Fatalf("Expected the deleted file to be restored, got %s", content)
+		} else if content := readFile(t, "site/data/prices.csv"); content != "id,price\napple,1\n" {
+			t.Fatalf("Expected the table to be restored, got %q", content)
+		}
+
+		if count, _ := provider.Count("data", nil, nil); count != 2 {
+			t.Fatalf("Expected 2 records after the rollback, got %d", count)
+		} else if record, err := dataSource.Get("data", "a.json", nil); err != nil || record.Get("name") != "Alpha" {
+			t.Fatalf("Expected the updated record to be restored, got %v (%v)", record, err)
+		} else if _, err := dataSource.Get("prices", "banana", nil); err == nil {
+			t.Fatal("Expected the inserted row to be removed")
+		}
+
+		err = provider.Batch([]BatchOperation{
+			{Action: BatchInsert, CollectionId: "data", Record: &Record{Id: "c.json", Data: map[string]any{"name": "C"}}},
+			{Action: BatchDelete, CollectionId: "data", Query: query.Eq("id", "b.json")},
+			{Action: BatchInsert, CollectionId: "prices", Record: &Record{Id: "banana", Data: map[string]any{"price": 2}}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if exists, _ := afero.Exists(fs, "site/data/b.json"); exists {
+			t.Fatal("Expected the deleted file to be removed")
+		} else if content := readFile(t, "site/data/prices.csv"); content != "id,price\napple,1\nbanana,2\n" {
+			t.Fatalf("Unexpected table: %q", content)
+		}
+	})
+
+	t.Run("Delete errors", func(t *testing.T) {
+		provider.FS = afero.NewReadOnlyFs(fs)
+		defer func() {
+			provider.FS = fs
+		}()
+
+		if err := dataSource.Delete("data", query.Eq("id", "c.json"), nil); err == nil {
+			t.Fatal("Expected the delete to fail")
+		} else if _, err := dataSource.Get("data", "c.json", nil); err != nil {
+			t.Fatalf("Expected the record to be kept, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,415 @@
+package sulat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// CompositeDataSourceProvider is a data source provider that overlays the
+// collections of multiple data sources. A record of a layer shadows the
+// records with the same id of the layers after it. Only the writable layer
+// is written to, records of the other layers are read-only.
+type CompositeDataSourceProvider struct {
+	// Layers is the list of data source ids ordered from the highest
+	// precedence
+	Layers []string
+
+	// Writable is the id of the data source where the records are written
+	// to. Defaults to the first layer.
+	Writable string
+
+	layers []*DataSource
+}
+
+func injectConfigToCompositeProvider(p *CompositeDataSourceProvider, config map[string]any) {
+	if rawLayers, ok := config["layers"]; ok {
+		if layers, ok := rawLayers.([]string); ok {
+			p.Layers = slices.Clone(layers)
+		} else if layers, ok := rawLayers.([]any); ok {
+			p.Layers = make([]string, 0, len(layers))
+			for _, layer := range layers {
+				layer, ok := layer.(string)
+				if !ok {
+					continue
+				}
+				p.Layers = append(p.Layers, layer)
+			}
+		}
+	}
+
+	if rawWritable, ok := config["writable"]; ok {
+		if writable, ok := rawWritable.(string); ok {
+			p.Writable = writable
+		}
+	}
+}
+
+func (p *CompositeDataSourceProvider) Properties() DataSourceProviderProperties {
+	return DataSourceProviderProperties{
+		Id:      "composite",
+		Name:    "Composite",
+		Version: "1.0.0",
+		ConfigSchema: Schema{
+			RepeaterSchemaField{
+				BaseField: BaseField{
+					FieldName:  "layers",
+					FieldLabel: "Layers",
+					Required:   true,
+				},
+				BaseSchemaField: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "data_source_id",
+						FieldLabel: "Data source ID",
+						Required:   true,
+					},
+				},
+				MinLength: 1,
+				MaxLength: 16,
+			},
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "writable",
+					FieldLabel: "Writable layer",
+				},
+			},
+		},
+	}
+}
+
+func (p *CompositeDataSourceProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	newProvider := &CompositeDataSourceProvider{}
+	injectConfigToCompositeProvider(newProvider, config)
+
+	if len(newProvider.Layers) == 0 {
+		return nil, errors.New("no layers specified")
+	} else if len(newProvider.Writable) == 0 {
+		newProvider.Writable = newProvider.Layers[0]
+	} else if !slices.Contains(newProvider.Layers, newProvider.Writable) {
+		return nil, fmt.Errorf("writable layer %s is not one of the layers", newProvider.Writable)
+	}
+
+	return newProvider, nil
+}
+
+func (p *CompositeDataSourceProvider) Initialize(i *Instance) error {
+	// layers referring back to the composite data source would recurse
+	// endlessly when reading the records
+	if cycle := p.findCycle(i, p.Layers, map[string]bool{}); len(cycle) == 1 {
+		return fmt.Errorf("layer %s refers to the composite data source itself", cycle[0])
+	} else if len(cycle) > 1 {
+		return fmt.Errorf("layers %s refer back to the composite data source", strings.Join(cycle, " -> "))
+	}
+
+	p.layers = make([]*DataSource, 0, len(p.Layers))
+	for _, layerId := range p.Layers {
+		layer, err := i.FindDataSource(layerId)
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layerId, err)
+		} else if layer.DataSourceProvider == nil {
+			return fmt.Errorf("layer %s has no provider", layerId)
+		}
+		p.layers = append(p.layers, layer)
+	}
+	return nil
+}
+
+// findCycle returns the chain of layer ids leading back to the composite
+// data source through the layers of the nested composite data sources
+func (p *CompositeDataSourceProvider) findCycle(i *Instance, layerIds []string, visited map[string]bool) []string {
+	for _, layerId := range layerIds {
+		if visited[layerId] {
+			continue
+		}
+		visited[layerId] = true
+
+		layer, err := i.FindDataSource(layerId)
+		if err != nil {
+			continue
+		} else if layer.DataSourceProvider == DataSourceProvider(p) {
+			return []string{layerId}
+		}
+
+		if composite, ok := layer.DataSourceProvider.(*CompositeDataSourceProvider); ok {
+			if cycle := p.findCycle(i, composite.Layers, visited); cycle != nil {
+				return append([]string{layerId}, cycle...)
+			}
+		}
+	}
+	return nil
+}
+
+// Ping checks whether all of the layers can be reached
+func (p *CompositeDataSourceProvider) Ping() error {
+	for _, layer := range p.layers {
+		if err := layer.Ping(); err != nil {
+			return fmt.Errorf("layer %s: %w", layer.Id, err)
+		}
+	}
+	return nil
+}
+
+// Close does nothing since the layers are data sources of their own
+func (p *CompositeDataSourceProvider) Close() error {
+	return nil
+}
+
+// Status reports the health of the layers
+func (p *CompositeDataSourceProvider) Status() DataSourceStatus {
+	layers := make(map[string]any, len(p.layers))
+	for _, layer := range p.layers {
+		layers[layer.Id] = layer.Status().Healthy
+	}
+	return newDataSourceStatus(p.Ping(), map[string]any{
+		"layers":   layers,
+		"writable": p.Writable,
+	})
+}
+
+// isNotFound checks if the error is a not found error of a layer
+func isNotFound(err error) bool {
+	rErr, ok := err.(*ResponseError)
+	return ok && rErr.StatusCode == http.StatusNotFound
+}
+
+// withLayer returns a copy of the record marked with the layer it came from
+func withLayer(record *Record, layer *DataSource) *Record {
+	return &Record{
+		Id:         record.Id,
+		Data:       record.Data,
+		Codec:      record.Codec,
+		Collection: record.Collection,
+		Layer:      layer.Id,
+	}
+}
+
+// owner returns the index of the first layer containing the record
+// and the record itself
+func (p *CompositeDataSourceProvider) owner(collectionId string, id string) (int, *Record, error) {
+	for idx, layer := range p.layers {
+		record, err := layer.Get(collectionId, id, nil)
+		if isNotFound(err) {
+			continue
+		} else if err != nil {
+			return -1, nil, err
+		}
+		return idx, record, nil
+	}
+	return -1, nil, NewResponseError(http.StatusNotFound, "record not found")
+}
+
+// writableLayer returns the index of the writable layer
+func (p *CompositeDataSourceProvider) writableLayer() int {
+	return slices.IndexFunc(p.layers, func(layer *DataSource) bool {
+		return layer.Id == p.Writable
+	})
+}
+
+func (p *CompositeDataSourceProvider) Get(collectionId string, id string, opts map[string]any) (*Record, error) {
+	idx, record, err := p.owner(collectionId, id)
+	if err != nil {
+		return nil, err
+	}
+	return withLayer(record, p.layers[idx]), nil
+}
+
+// hasCollection checks whether the layer has the collection. Providers
+// which can count the records are used to avoid loading the records.
+func hasCollection(layer *DataSource, collectionId string) (bool, error) {
+	var err error
+	if counter, ok := layer.DataSourceProvider.(DataSourceCounter); ok {
+		_, err = counter.Count(collectionId, nil, nil)
+	} else {
+		_, err = layer.Find(collectionId, nil, nil)
+	}
+
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// matchRecords returns the records of the layers matching the query. A
+// record matched in a layer is only included if no layer before it
+// contains a record with the same id.
+func (p *CompositeDataSourceProvider) matchRecords(collectionId string, q *query.Query) ([]*Record, error) {
+	if err := ValidateQuery(q); err != nil {
+		return nil, err
+	}
+
+	// the options are applied once the records of the layers are merged
+	var filter *query.Query
+	if q != nil && len(q.Operator) != 0 {
+		filter = q.WithoutOptions(maps.Keys(q.Options)...)
+	}
+
+	found := []*Record{}
+	collectionFound := false
+
+	// shadowed are the ids of the records of the layers before the
+	// current layer including the records which have not matched
+	shadowed := map[string]bool{}
+
+	for idx, layer := range p.layers {
+		records, err := layer.Find(collectionId, filter, nil)
+		if err == nil {
+			collectionFound = true
+		} else if !isNotFound(err) {
+			return nil, fmt.Errorf("layer %s: %w", layer.Id, err)
+		}
+
+		for _, record := range records {
+			if !shadowed[record.Id] {
+				found = append(found, withLayer(record, layer))
+			}
+		}
+
+		if idx == len(p.layers)-1 {
+			// the collection may only exist in the other layers
+			if !collectionFound && filter != nil {
+				exists, err := hasCollection(layer, collectionId)
+				if err != nil {
+					return nil, fmt.Errorf("layer %s: %w", layer.Id, err)
+				}
+				collectionFound = exists
+			}
+			break
+		}
+
+		// the records of the layer are listed once to shadow the
+		// records of the layers after it
+		if filter != nil {
+			records, err = layer.Find(collectionId, nil, nil)
+			if err == nil {
+				collectionFound = true
+			} else if !isNotFound(err) {
+				return nil, fmt.Errorf("layer %s: %w", layer.Id, err)
+			}
+		}
+
+		for _, record := range records {
+			shadowed[record.Id] = true
+		}
+	}
+
+	if !collectionFound {
+		return nil, NewResponseError(http.StatusNotFound, "collection not found")
+	}
+
+	slices.SortFunc(found, func(a, b *Record) int {
+		if a.Id < b.Id {
+			return -1
+		} else if a.Id > b.Id {
+			return 1
+		}
+		return 0
+	})
+	return found, nil
+}
+
+func (p *CompositeDataSourceProvider) Find(collectionId string, q *query.Query, opts map[string]any) ([]*Record, error) {
+	found, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if q != nil && len(found) == 0 {
+		return nil, NewResponseError(http.StatusNotFound, "no records found")
+	}
+
+	return ApplyQueryOptions(q, found)
+}
+
+func (p *CompositeDataSourceProvider) Count(collectionId string, q *query.Query, opts map[string]any) (int, error) {
+	found, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return 0, err
+	}
+	return len(found), nil
+}
+
+// writeRecord returns a copy of the record to be written to a layer
+func writeRecord(record *Record) *Record {
+	return &Record{
+		Id:         record.Id,
+		Data:       record.Data,
+		Codec:      record.Codec,
+		Collection: record.Collection,
+	}
+}
+
+func (p *CompositeDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
+	if len(record.Id) != 0 {
+		if _, _, err := p.owner(collectionId, record.Id); err == nil {
+			return NewResponseError(http.StatusConflict, "record already exists")
+		} else if !isNotFound(err) {
+			return err
+		}
+	}
+
+	writable := p.layers[p.writableLayer()]
+	if err := writable.Insert(collectionId, writeRecord(record), opts); err != nil {
+		return err
+	}
+
+	record.Layer = writable.Id
+	return nil
+}
+
+func (p *CompositeDataSourceProvider) Update(collectionId string, record *Record, opts map[string]any) error {
+	ownerIdx, _, err := p.owner(collectionId, record.Id)
+	if err != nil {
+		return err
+	}
+
+	writableIdx := p.writableLayer()
+	writable := p.layers[writableIdx]
+	switch {
+	case ownerIdx < writableIdx:
+		return NewResponseError(http.StatusForbidden, fmt.Sprintf("record is provided by the read-only layer %s", p.layers[ownerIdx].Id))
+	case ownerIdx > writableIdx:
+		// records of the layers after the writable layer are
+		// overridden by a copy in the writable layer
+		err = writable.Insert(collectionId, writeRecord(record), opts)
+	default:
+		err = writable.Update(collectionId, writeRecord(record), opts)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	record.Layer = writable.Id
+	return nil
+}
+
+func (p *CompositeDataSourceProvider) Delete(collectionId string, q *query.Query, opts map[string]any) error {
+	if q == nil {
+		return NewResponseError(http.StatusBadRequest, "query is required")
+	}
+
+	found, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]any, 0, len(found))
+	for _, record := range found {
+		if record.Layer != p.Writable {
+			return NewResponseError(http.StatusForbidden, fmt.Sprintf("record %s is provided by the read-only layer %s", record.Id, record.Layer))
+		}
+		ids = append(ids, record.Id)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	writable := p.layers[p.writableLayer()]
+	return writable.Delete(collectionId, query.In("id", ids), opts)
+}
@@ -0,0 +1,269 @@
+package sulat
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+// countingProvider counts the reads made to the memory provider
+type countingProvider struct {
+	*MemoryDataSourceProvider
+	reads *int
+}
+
+func (p *countingProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	provider, err := p.MemoryDataSourceProvider.WithConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &countingProvider{provider.(*MemoryDataSourceProvider), p.reads}, nil
+}
+
+func (p *countingProvider) Get(collectionId string, id string, opts map[string]any) (*Record, error) {
+	*p.reads++
+	return p.MemoryDataSourceProvider.Get(collectionId, id, opts)
+}
+
+func (p *countingProvider) Find(collectionId string, q *query.Query, opts map[string]any) ([]*Record, error) {
+	*p.reads++
+	return p.MemoryDataSourceProvider.Find(collectionId, q, opts)
+}
+
+func TestCompositeDataSourceProvider(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := inst.NewDataSource("theme", "Theme", &MemoryDataSourceProvider{
+		Fixtures: map[string][]*Record{
+			"pages": {
+				{Id: "about", Data: map[string]any{"title": "About"}},
+				{Id: "hello", Data: map[string]any{"title": "Hello from the theme"}},
+			},
+		},
+	}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	themeProvider := theme.DataSourceProvider.(*MemoryDataSourceProvider)
+
+	if _, err := inst.NewDataSource("local", "Local", &MemoryDataSourceProvider{
+		Fixtures: map[string][]*Record{
+			"pages": {
+				{Id: "hello", Data: map[string]any{"title": "Hello"}},
+				{Id: "news", Data: map[string]any{"title": "News"}},
+			},
+			"drafts": {},
+		},
+	}, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dataSource, err := inst.NewDataSource("site", "Site", &CompositeDataSourceProvider{}, map[string]any{
+		"layers": []string{"local", "theme"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findLayers := func(t *testing.T, rawQuery string) map[string]string {
+		t.Helper()
+		q, err := query.ParseFromString(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := dataSource.Find("pages", q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		layers := map[string]string{}
+		for _, record := range records {
+			layers[record.Id] = record.Layer
+		}
+		return layers
+	}
+
+	t.Run("Merges the layers", func(t *testing.T) {
+		layers := findLayers(t, "")
+		expected := map[string]string{"about": "theme", "hello": "local", "news": "local"}
+		if len(layers) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, layers)
+		}
+		for id, layer := range expected {
+			if layers[id] != layer {
+				t.Errorf("Expected %s to come from %s, got %s", id, layer, layers[id])
+			}
+		}
+
+		record, err := dataSource.Get("pages", "hello", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Get("title") != "Hello" || record.Layer != "local" {
+			t.Fatalf("Expected the local record, got %v from %s", record.Data, record.Layer)
+		}
+
+		// shadowed records are not matched
+		q, _ := query.ParseFromString(`eq(title "Hello from the theme")`)
+		_, err = dataSource.Find("pages", q, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		if layers := findLayers(t, `{order:["title","desc"], limit:1}`); len(layers) != 1 || layers["news"] != "local" {
+			t.Fatalf("Expected options to apply to the merged records, got %v", layers)
+		}
+
+		if count, err := dataSource.DataSourceProvider.(DataSourceCounter).Count("pages", nil, nil); err != nil || count != 3 {
+			t.Fatalf("Expected 3 records, got %d (%v)", count, err)
+		}
+
+		// collections of a single layer are available
+		if records, err := dataSource.Find("drafts", nil, nil); err != nil || len(records) != 0 {
+			t.Fatalf("Expected an empty collection, got %v (%v)", records, err)
+		}
+
+		_, err = dataSource.Find("posts", nil, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error for unknown collection, got %v", err)
+		}
+	})
+
+	t.Run("Writes to the writable layer", func(t *testing.T) {
+		err := dataSource.Insert("pages", &Record{Id: "about", Data: map[string]any{"title": "Duplicate"}}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusConflict {
+			t.Fatalf("Expected conflict error, got %v", err)
+		}
+
+		record := &Record{Id: "contact", Data: map[string]any{"title": "Contact"}}
+		if err := dataSource.Insert("pages", record, nil); err != nil {
+			t.Fatal(err)
+		} else if record.Layer != "local" {
+			t.Fatalf("Expected the record to be written to local, got %s", record.Layer)
+		}
+
+		// updating a record of the theme overrides it in the local layer
+		if err := dataSource.Update("pages", &Record{Id: "about", Data: map[string]any{"title": "About us"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if record, err := dataSource.Get("pages", "about", nil); err != nil {
+			t.Fatal(err)
+		} else if record.Get("title") != "About us" || record.Layer != "local" {
+			t.Fatalf("Expected the overridden record, got %v from %s", record.Data, record.Layer)
+		}
+
+		if record, _ := themeProvider.Get("pages", "about", nil); record.Get("title") != "About" {
+			t.Fatalf("Expected the theme record to be unchanged, got %v", record.Data)
+		}
+
+		// deleting an override reveals the record of the theme
+		if err := dataSource.Delete("pages", query.Eq("id", "hello"), nil); err != nil {
+			t.Fatal(err)
+		} else if record, _ := dataSource.Get("pages", "hello", nil); record.Layer != "theme" {
+			t.Fatalf("Expected the theme record to be revealed, got %s", record.Layer)
+		}
+
+		err = dataSource.Delete("pages", query.Eq("id", "hello"), nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusForbidden {
+			t.Fatalf("Expected forbidden error for deleting a read-only record, got %v", err)
+		}
+	})
+
+	t.Run("Reads each layer once", func(t *testing.T) {
+		reads := 0
+		pages := []*Record{}
+		for i := 0; i < 20; i++ {
+			pages = append(pages, &Record{Id: fmt.Sprintf("page-%d", i), Data: map[string]any{"title": "Page"}})
+		}
+
+		if _, err := inst.NewDataSource("counted", "Counted", &countingProvider{
+			MemoryDataSourceProvider: &MemoryDataSourceProvider{Fixtures: map[string][]*Record{"pages": pages, "drafts": {}}},
+			reads:                    &reads,
+		}, map[string]any{}); err != nil {
+			t.Fatal(err)
+		}
+
+		counted, err := inst.NewDataSource("site-counted", "Site counted", &CompositeDataSourceProvider{}, map[string]any{
+			"layers": []string{"counted", "theme"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if records, err := counted.Find("pages", query.Eq("title", "About"), nil); err != nil || len(records) != 1 {
+			t.Fatalf("Expected the theme record, got %v (%v)", records, err)
+		} else if reads != 2 {
+			t.Fatalf("Expected the layer to be read twice, got %d reads", reads)
+		}
+
+		// the last layer is checked for the collection without listing
+		// its records
+		lastCounted, err := inst.NewDataSource("theme-counted", "Theme counted", &CompositeDataSourceProvider{}, map[string]any{
+			"layers": []string{"theme", "counted"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reads = 0
+		_, err = lastCounted.Find("drafts", query.Eq("title", "Draft"), nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound || rErr.Message != "no records found" {
+			t.Fatalf("Expected no records found error, got %v", err)
+		} else if reads != 1 {
+			t.Fatalf("Expected the layer to be read once, got %d reads", reads)
+		}
+	})
+
+	t.Run("Read-only layers before the writable layer", func(t *testing.T) {
+		lowerWritable, err := inst.NewDataSource("site-theme", "Site theme", &CompositeDataSourceProvider{}, map[string]any{
+			"layers":   []any{"local", "theme"},
+			"writable": "theme",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = lowerWritable.Update("pages", &Record{Id: "news", Data: map[string]any{"title": "Old news"}}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusForbidden {
+			t.Fatalf("Expected forbidden error, got %v", err)
+		}
+
+		if _, err := (&CompositeDataSourceProvider{}).WithConfig(map[string]any{"layers": []string{"local"}, "writable": "theme"}); err == nil {
+			t.Fatal("Expected error for a writable layer outside of the layers")
+		}
+	})
+
+	t.Run("Rejects cycles", func(t *testing.T) {
+		if _, err := inst.NewDataSource("self", "Self", &CompositeDataSourceProvider{}, map[string]any{
+			"layers": []string{"local", "self"},
+		}); err == nil {
+			t.Fatal("Expected error for a layer referring to itself")
+		}
+
+		outer, err := inst.NewDataSource("outer", "Outer", &CompositeDataSourceProvider{}, map[string]any{
+			"layers": []string{"site"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// site -> outer -> site
+		dataSource.Config = map[string]any{"layers": []string{"outer", "local"}}
+		if err := dataSource.Reinitialize(); err == nil || err.Error() != "layers outer -> site refer back to the composite data source" {
+			t.Fatalf("Expected cycle error, got %v", err)
+		} else if dataSource.Status().Healthy {
+			t.Fatal("Expected the data source to be reported as unhealthy")
+		}
+
+		// the rejected layers are not read
+		if _, err := outer.Find("pages", nil, nil); !isNotFound(err) {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,619 @@
+package sulat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	defaultGitAuthorName      = "Sulat"
+	defaultGitAuthorEmail     = "sulat@localhost"
+	defaultGitMessageTemplate = `{{.Action}} {{.CollectionId}}/{{join .RecordIds ", "}}`
+)
+
+var gitRevisionPattern = regexp.MustCompile(`^[0-9A-Za-z_./~^@{}-]+$`)
+
+// GitCommitInfo is the data passed to the commit message template
+type GitCommitInfo struct {
+	// Action is either "create", "update", "delete", "restore" or "batch".
+	// Batches changing multiple collections have comma-separated
+	// collection ids.
+	Action       string
+	CollectionId string
+	RecordIds    []string
+	Revision     string
+}
+
+// GitDataSourceProvider is a data source provider that stores the records
+// as files in a local Git repository. Every change to the records is
+// committed to the repository.
+type GitDataSourceProvider struct {
+	*FileDataSourceProvider
+
+	// Branch is the branch where changes are committed. The currently
+	// checked out branch is used if empty. Other branches are checked out
+	// in a separate worktree so the working tree of the repository is
+	// left untouched.
+	Branch string
+
+	// AuthorName and AuthorEmail are used as the author and committer
+	// of the commits
+	AuthorName  string
+	AuthorEmail string
+
+	// MessageTemplate is the text/template of the commit messages
+	// executed with GitCommitInfo
+	MessageTemplate string
+
+	// prefix is the path of the root relative to the top of the repository
+	prefix string
+
+	// repoRoot is the configured root. The root is moved into the worktree
+	// of the branch if the branch is not checked out in the repository.
+	repoRoot string
+	worktree string
+
+	message *template.Template
+
+	// gitMu serializes the changes made to the repository. The records of
+	// the embedded provider are guarded by its own lock.
+	gitMu sync.Mutex
+}
+
+func injectConfigToGitProvider(p *GitDataSourceProvider, config map[string]any) {
+	for key, dest := range map[string]*string{
+		"branch":           &p.Branch,
+		"author_name":      &p.AuthorName,
+		"author_email":     &p.AuthorEmail,
+		"message_template": &p.MessageTemplate,
+	} {
+		if rawValue, ok := config[key]; ok {
+			if value, ok := rawValue.(string); ok {
+				*dest = value
+			}
+		}
+	}
+}
+
+func (p *GitDataSourceProvider) Properties() DataSourceProviderProperties {
+	schema := slices.Clone((&FileDataSourceProvider{}).Properties().ConfigSchema)
+	schema = append(schema,
+		StringSchemaField{
+			BaseField: BaseField{
+				FieldName:  "branch",
+				FieldLabel: "Branch",
+			},
+		},
+		StringSchemaField{
+			BaseField: BaseField{
+				FieldName:  "author_name",
+				FieldLabel: "Author name",
+			},
+		},
+		StringSchemaField{
+			BaseField: BaseField{
+				FieldName:  "author_email",
+				FieldLabel: "Author email",
+			},
+		},
+		StringSchemaField{
+			BaseField: BaseField{
+				FieldName:  "message_template",
+				FieldLabel: "Commit message template",
+			},
+		},
+	)
+
+	return DataSourceProviderProperties{
+		Id:           "git",
+		Name:         "Git",
+		Version:      "1.0.0",
+		ConfigSchema: schema,
+	}
+}
+
+func (p *GitDataSourceProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	// git works with the files on disk so the OS file system is always used
+	fsProvider, err := (&FileDataSourceProvider{FS: afero.NewOsFs()}).WithConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	newProvider := &GitDataSourceProvider{
+		FileDataSourceProvider: fsProvider.(*FileDataSourceProvider),
+		AuthorName:             defaultGitAuthorName,
+		AuthorEmail:            defaultGitAuthorEmail,
+		MessageTemplate:        defaultGitMessageTemplate,
+	}
+
+	injectConfigToGitProvider(newProvider, config)
+
+	newProvider.message, err = template.New("message").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Parse(newProvider.MessageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+
+	return newProvider, nil
+}
+
+func (p *GitDataSourceProvider) Initialize(i *Instance) error {
+	if len(p.Root) == 0 {
+		return errors.New("no root specified")
+	}
+
+	prefix, err := p.git("rev-parse", "--show-prefix")
+	if err != nil {
+		return fmt.Errorf("%s is not within a git repository: %w", p.Root, err)
+	}
+	p.prefix = strings.TrimSpace(prefix)
+
+	if err := p.checkoutBranch(); err != nil {
+		return err
+	}
+
+	// records are imported only after the branch is checked out
+	return p.FileDataSourceProvider.Initialize(i)
+}
+
+// checkoutBranch checks out the branch in a worktree located in the git
+// directory of the repository if it is not the currently checked out
+// branch. The branch is created from HEAD if it does not exist.
+func (p *GitDataSourceProvider) checkoutBranch() error {
+	if len(p.Branch) == 0 {
+		return nil
+	} else if !gitRevisionPattern.MatchString(p.Branch) || strings.HasPrefix(p.Branch, "-") {
+		return fmt.Errorf("invalid branch name: %s", p.Branch)
+	}
+
+	current, _ := p.git("symbolic-ref", "--short", "HEAD")
+	if strings.TrimSpace(current) == p.Branch {
+		return nil
+	}
+
+	gitDir, err := p.git("rev-parse", "--git-common-dir")
+	if err != nil {
+		return err
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(p.Root, gitDir)
+	}
+
+	worktree := filepath.Join(gitDir, "sulat", "worktrees", strings.ReplaceAll(p.Branch, "/", "-"))
+	if _, err := os.Stat(worktree); err != nil {
+		// worktrees which were removed without git are pruned first
+		if _, err := p.git("worktree", "prune"); err != nil {
+			return err
+		}
+
+		args := []string{"worktree", "add", "-q", worktree, p.Branch}
+		if _, err := p.git("rev-parse", "--verify", "--quiet", "refs/heads/"+p.Branch); err != nil {
+			if _, err := p.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+				return fmt.Errorf("cannot create branch %s: the repository has no commits", p.Branch)
+			}
+			args = []string{"worktree", "add", "-q", "-b", p.Branch, worktree, "HEAD"}
+		}
+
+		if _, err := p.git(args...); err != nil {
+			return err
+		}
+	}
+
+	p.repoRoot = p.Root
+	p.worktree = worktree
+	p.Root = filepath.Join(worktree, filepath.FromSlash(p.prefix))
+	return p.FS.MkdirAll(p.Root, 0755)
+}
+
+// git runs a git command within the root directory
+func (p *GitDataSourceProvider) git(args ...string) (string, error) {
+	return p.gitAt(p.Root, args...)
+}
+
+// gitAt runs a git command within the directory
+func (p *GitDataSourceProvider) gitAt(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+p.AuthorName,
+		"GIT_AUTHOR_EMAIL="+p.AuthorEmail,
+		"GIT_COMMITTER_NAME="+p.AuthorName,
+		"GIT_COMMITTER_EMAIL="+p.AuthorEmail,
+		"GIT_TERMINAL_PROMPT=0",
+	)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() != 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// changedPaths returns the paths with uncommitted changes relative to the root
+func (p *GitDataSourceProvider) changedPaths(paths []string) ([]string, error) {
+	output, err := p.git(append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		} else if entry[0] == 'R' || entry[0] == 'C' {
+			// renames and copies are followed by the original path
+			i++
+		}
+
+		// porcelain paths are relative to the top of the repository
+		changed = append(changed, filepath.FromSlash(strings.TrimPrefix(entry[3:], p.prefix)))
+	}
+	return changed, nil
+}
+
+// commit stages and commits the changes made to the files of the records
+func (p *GitDataSourceProvider) commit(info GitCommitInfo, filenames []string) error {
+	changed, err := p.changedPaths(filenames)
+	if err != nil {
+		return err
+	} else if len(changed) == 0 {
+		return nil
+	}
+
+	if _, err := p.git(append([]string{"add", "-A", "--"}, changed...)...); err != nil {
+		return err
+	}
+
+	message := &strings.Builder{}
+	if err := p.message.Execute(message, info); err != nil {
+		return err
+	}
+
+	_, err = p.git(append([]string{"commit", "-q", "-m", message.String(), "--"}, changed...)...)
+	return err
+}
+
+func (p *GitDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	if err := p.FileDataSourceProvider.Insert(collectionId, record, opts); err != nil {
+		return err
+	}
+
+	filename, err := p.lookupRecordPath(collectionId, record.Id)
+	if err != nil {
+		return err
+	}
+
+	return p.commit(GitCommitInfo{
+		Action:       "create",
+		CollectionId: collectionId,
+		RecordIds:    []string{record.Id},
+	}, []string{filename})
+}
+
+func (p *GitDataSourceProvider) Update(collectionId string, record *Record, opts map[string]any) error {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	// records with path templates are moved when their paths change
+	oldFilename, err := p.lookupRecordPath(collectionId, record.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := p.FileDataSourceProvider.Update(collectionId, record, opts); err != nil {
+		return err
+	}
+
+	filename, err := p.lookupRecordPath(collectionId, record.Id)
+	if err != nil {
+		return err
+	}
+
+	filenames := []string{filename}
+	if oldFilename != filename {
+		filenames = append(filenames, oldFilename)
+	}
+
+	return p.commit(GitCommitInfo{
+		Action:       "update",
+		CollectionId: collectionId,
+		RecordIds:    []string{record.Id},
+	}, filenames)
+}
+
+func (p *GitDataSourceProvider) Delete(collectionId string, q *query.Query, opts map[string]any) error {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	if q == nil {
+		return NewResponseError(http.StatusBadRequest, "query is required")
+	} else if err := ValidateQuery(q); err != nil {
+		return err
+	}
+
+	p.FileDataSourceProvider.mu.RLock()
+	filenames, ids, err := p.matchFilenames(collectionId, q)
+
+	// rows of tabular collections share the file of the table
+	paths := []string{}
+	for _, filename := range filenames {
+		if path := p.recordPath(collectionId, filename); !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	p.FileDataSourceProvider.mu.RUnlock()
+
+	if err != nil {
+		return err
+	} else if len(filenames) == 0 {
+		return nil
+	}
+
+	if err := p.FileDataSourceProvider.Delete(collectionId, q, opts); err != nil {
+		return err
+	}
+
+	return p.commit(GitCommitInfo{
+		Action:       "delete",
+		CollectionId: collectionId,
+		RecordIds:    ids,
+	}, paths)
+}
+
+// Batch applies the operations as a single transaction and commits the
+// changes made by the operations in a single commit
+func (p *GitDataSourceProvider) Batch(ops []BatchOperation, opts map[string]any) error {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	filenames, ids, err := p.batch(ops)
+	if err != nil {
+		return err
+	} else if len(filenames) == 0 {
+		return nil
+	}
+
+	collectionIds := []string{}
+	for _, op := range ops {
+		if !slices.Contains(collectionIds, op.CollectionId) {
+			collectionIds = append(collectionIds, op.CollectionId)
+		}
+	}
+
+	return p.commit(GitCommitInfo{
+		Action:       "batch",
+		CollectionId: strings.Join(collectionIds, ","),
+		RecordIds:    ids,
+	}, filenames)
+}
+
+// Ping checks whether the root of the data source is inside a Git repository
+func (p *GitDataSourceProvider) Ping() error {
+	if err := p.FileDataSourceProvider.Ping(); err != nil {
+		return err
+	} else if _, err := p.git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Close removes the worktree of the branch so the branch can be checked
+// out elsewhere
+func (p *GitDataSourceProvider) Close() error {
+	if err := p.FileDataSourceProvider.Close(); err != nil {
+		return err
+	} else if len(p.worktree) == 0 {
+		return nil
+	}
+
+	if _, err := p.gitAt(p.repoRoot, "worktree", "remove", p.worktree); err != nil {
+		return err
+	}
+
+	p.Root = p.repoRoot
+	p.worktree = ""
+	return nil
+}
+
+// Status reports the health of the data source and the branch where the
+// changes are committed
+func (p *GitDataSourceProvider) Status() DataSourceStatus {
+	status := p.FileDataSourceProvider.Status()
+	if err := p.Ping(); err != nil {
+		status.Healthy = false
+		status.Message = err.Error()
+	}
+
+	branch := p.Branch
+	if len(branch) == 0 {
+		if output, err := p.git("symbolic-ref", "--short", "HEAD"); err == nil {
+			branch = strings.TrimSpace(output)
+		}
+	}
+
+	status.Details["branch"] = branch
+	if len(p.worktree) != 0 {
+		status.Details["worktree"] = p.worktree
+	}
+	return status
+}
+
+// lookupRecordPath returns the path of the file of the record relative to
+// the root
+func (p *GitDataSourceProvider) lookupRecordPath(collectionId string, id string) (string, error) {
+	p.FileDataSourceProvider.mu.RLock()
+	defer p.FileDataSourceProvider.mu.RUnlock()
+
+	filename, _, err := p.fetchRecord(collectionId, id)
+	if err != nil {
+		return "", err
+	}
+	return p.recordPath(collectionId, filename), nil
+}
+
+// recordFilename returns the filename of the record. Deleted records are
+// assumed to be stored in the same location where new records are saved.
+func (p *GitDataSourceProvider) recordFilename(collectionId string, id string) (string, error) {
+	p.FileDataSourceProvider.mu.RLock()
+	defer p.FileDataSourceProvider.mu.RUnlock()
+
+	if _, isTable := p.tables[collectionId]; isTable {
+		return "", NewResponseError(http.StatusNotImplemented, "history is not supported for tabular collections")
+	}
+
+	filename, _, err := p.fetchRecord(collectionId, id)
+	if err == nil {
+		return filename, nil
+	} else if _, found := p.records[collectionId]; !found {
+		return "", err
+	}
+	return p.defaultRecordFilename(collectionId, &Record{Id: id})
+}
+
+// resolveRevision resolves the revision into a commit hash
+func (p *GitDataSourceProvider) resolveRevision(revision string) (string, error) {
+	if !gitRevisionPattern.MatchString(revision) || strings.HasPrefix(revision, "-") {
+		return "", NewResponseError(http.StatusBadRequest, "invalid revision")
+	}
+
+	hash, err := p.git("rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	if err != nil {
+		return "", NewResponseError(http.StatusNotFound, "revision not found")
+	}
+	return strings.TrimSpace(hash), nil
+}
+
+func (p *GitDataSourceProvider) History(collectionId string, id string, opts map[string]any) ([]*RecordRevision, error) {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	filename, err := p.recordFilename(collectionId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := p.git("log", "--follow", "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e", "--", filename)
+	if err != nil {
+		// repositories without commits have no history
+		if _, headErr := p.git("rev-parse", "--verify", "--quiet", "HEAD"); headErr != nil {
+			return []*RecordRevision{}, nil
+		}
+		return nil, err
+	}
+
+	revisions := []*RecordRevision{}
+	for _, entry := range strings.Split(output, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(entry), "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		revisions = append(revisions, &RecordRevision{
+			Id:      fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Message: fields[4],
+		})
+	}
+
+	if len(revisions) == 0 {
+		return nil, NewResponseError(http.StatusNotFound, "record has no history")
+	}
+	return revisions, nil
+}
+
+func (p *GitDataSourceProvider) Diff(collectionId string, id string, revision string, opts map[string]any) (string, error) {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	filename, err := p.recordFilename(collectionId, id)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := p.resolveRevision(revision)
+	if err != nil {
+		return "", err
+	}
+
+	return p.git("show", "--format=", "--patch", hash, "--", filename)
+}
+
+func (p *GitDataSourceProvider) Restore(collectionId string, id string, revision string, opts map[string]any) (*Record, error) {
+	p.gitMu.Lock()
+	defer p.gitMu.Unlock()
+
+	filename, err := p.recordFilename(collectionId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := p.resolveRevision(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := p.git("show", hash+":./"+filepath.ToSlash(filename))
+	if err != nil {
+		return nil, NewResponseError(http.StatusNotFound, "record not found in revision")
+	}
+
+	codec, err := p.codecs.FindByFileName(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := codec.Deserialize(id, strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.lookupRecordPath(collectionId, id); err == nil {
+		err = p.FileDataSourceProvider.Update(collectionId, record, opts)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := p.FileDataSourceProvider.Insert(collectionId, record, opts); err != nil {
+		return nil, err
+	}
+
+	if err := p.commit(GitCommitInfo{
+		Action:       "restore",
+		CollectionId: collectionId,
+		RecordIds:    []string{id},
+		Revision:     hash,
+	}, []string{filename}); err != nil {
+		return nil, err
+	}
+
+	return p.FileDataSourceProvider.Get(collectionId, id, opts)
+}
@@ -0,0 +1,249 @@
+package sulat
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+func TestGitDataSourceProvider(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repoDir := t.TempDir()
+	runGit := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repoDir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Tester", "GIT_AUTHOR_EMAIL=tester@localhost",
+			"GIT_COMMITTER_NAME=Tester", "GIT_COMMITTER_EMAIL=tester@localhost",
+		)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %s", strings.Join(args, " "), output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	runGit("init", "-q", "-b", "main")
+	if err := os.MkdirAll(filepath.Join(repoDir, "content", "posts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "content", "posts", "hello.md"), []byte("Hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit("add", "-A")
+	runGit("commit", "-q", "-m", "initial content")
+
+	// uncommitted changes of other files are left untouched
+	if err := os.WriteFile(filepath.Join(repoDir, "notes.txt"), []byte("draft"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit("add", "notes.txt")
+
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataSource, err := inst.NewDataSource("repo", "Repository", &GitDataSourceProvider{}, map[string]any{
+		"root": filepath.Join(repoDir, "content"),
+		"collections": map[string]any{
+			"posts": "posts/*.md",
+		},
+		"branch":           "content",
+		"author_name":      "Editor",
+		"author_email":     "editor@example.com",
+		"message_template": "{{.Action}}({{.CollectionId}}): {{join .RecordIds \", \"}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := dataSource.DataSourceProvider.(*GitDataSourceProvider)
+
+	// the branch is checked out in a separate worktree
+	if branch := runGit("symbolic-ref", "--short", "HEAD"); branch != "main" {
+		t.Fatalf("Expected branch 'main' to stay checked out, got %s", branch)
+	} else if branch := runGit("-C", provider.Root, "symbolic-ref", "--short", "HEAD"); branch != "content" {
+		t.Fatalf("Expected branch 'content' to be checked out in the worktree, got %s", branch)
+	}
+	root := provider.Root
+
+	t.Run("Commits per change", func(t *testing.T) {
+		if err := dataSource.Insert("posts", &Record{Id: "new.md", Data: map[string]any{"content": "First"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dataSource.Update("posts", &Record{Id: "hello.md", Data: map[string]any{"content": "Hello, world!"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dataSource.Delete("posts", query.Eq("id", "new.md"), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		log := runGit("log", "refs/heads/content", "--format=%an <%ae> %s")
+		expected := strings.Join([]string{
+			"Editor <editor@example.com> delete(posts): new.md",
+			"Editor <editor@example.com> update(posts): hello.md",
+			"Editor <editor@example.com> create(posts): new.md",
+			"Tester <tester@localhost> initial content",
+		}, "\n")
+		if log != expected {
+			t.Fatalf("Expected log:\n%s\ngot:\n%s", expected, log)
+		}
+
+		if _, err := os.Stat(filepath.Join(root, "posts", "new.md")); !os.IsNotExist(err) {
+			t.Fatalf("Expected deleted record to be removed from disk, got %v", err)
+		}
+
+		// the working tree of the repository is left untouched
+		if status := runGit("status", "--porcelain"); status != "A  notes.txt" {
+			t.Fatalf("Expected unrelated changes to be left staged, got %q", status)
+		} else if content, err := os.ReadFile(filepath.Join(repoDir, "content", "posts", "hello.md")); err != nil || string(content) != "Hello!" {
+			t.Fatalf("Expected the checked out file to be unchanged, got %q (%v)", content, err)
+		}
+	})
+
+	t.Run("History, diff and restore", func(t *testing.T) {
+		revisions, err := provider.History("posts", "hello.md", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(revisions) != 2 {
+			t.Fatalf("Expected 2 revisions, got %d", len(revisions))
+		} else if revisions[0].Message != "update(posts): hello.md" || revisions[1].Author != "Tester" {
+			t.Fatalf("Unexpected revisions: %+v %+v", revisions[0], revisions[1])
+		}
+
+		diff, err := provider.Diff("posts", "hello.md", revisions[0].Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if !strings.Contains(diff, "-Hello!") || !strings.Contains(diff, "+Hello, world!") {
+			t.Fatalf("Unexpected diff:\n%s", diff)
+		}
+
+		record, err := provider.Restore("posts", "hello.md", revisions[1].Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Data["content"] != "Hello!" {
+			t.Fatalf("Expected restored content, got %v", record.Data["content"])
+		}
+
+		content, err := os.ReadFile(filepath.Join(root, "posts", "hello.md"))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(content) != "Hello!" {
+			t.Fatalf("Expected restored file, got %q", content)
+		}
+
+		if subject := runGit("log", "-1", "refs/heads/content", "--format=%s"); subject != "restore(posts): hello.md" {
+			t.Fatalf("Expected restore commit, got %s", subject)
+		}
+
+		// deleted records can be restored from an older revision
+		deletedRevisions, err := provider.History("posts", "new.md", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(deletedRevisions) != 2 {
+			t.Fatalf("Expected 2 revisions of the deleted record, got %d", len(deletedRevisions))
+		}
+
+		if _, err := provider.Restore("posts", "new.md", deletedRevisions[1].Id, nil); err != nil {
+			t.Fatal(err)
+		} else if _, err := dataSource.Get("posts", "new.md", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, revision := range []string{"--output=/tmp/x", "does-not-exist"} {
+			if _, err := provider.Diff("posts", "hello.md", revision, nil); err == nil {
+				t.Errorf("Expected error for revision %q", revision)
+			}
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		head := runGit("rev-parse", "refs/heads/content")
+		err := provider.Batch([]BatchOperation{
+			{Action: BatchInsert, CollectionId: "posts", Record: &Record{Id: "batch.md", Data: map[string]any{"content": "Batch"}}},
+			{Action: BatchInsert, CollectionId: "posts", Record: &Record{Id: "new.md", Data: map[string]any{"content": "Duplicate"}}},
+		}, nil)
+		if err == nil {
+			t.Fatal("Expected the batch to fail")
+		} else if runGit("rev-parse", "refs/heads/content") != head {
+			t.Fatal("Expected failed batches to not be committed")
+		} else if _, err := os.Stat(filepath.Join(root, "posts", "batch.md")); !os.IsNotExist(err) {
+			t.Fatalf("Expected the batch to be rolled back, got %v", err)
+		}
+
+		err = provider.Batch([]BatchOperation{
+			{Action: BatchInsert, CollectionId: "posts", Record: &Record{Id: "batch.md", Data: map[string]any{"content": "Batch"}}},
+			{Action: BatchDelete, CollectionId: "posts", Query: query.Eq("id", "new.md")},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if subject := runGit("log", "-1", "refs/heads/content", "--format=%s"); subject != "batch(posts): batch.md, new.md" {
+			t.Fatalf("Expected batch commit, got %s", subject)
+		} else if count := runGit("rev-list", "--count", head+"..refs/heads/content"); count != "1" {
+			t.Fatalf("Expected a single commit, got %s", count)
+		}
+	})
+
+	t.Run("Writes during reloads", func(t *testing.T) {
+		done := make(chan struct{})
+		reloaded := make(chan error, 1)
+		go func() {
+			defer close(reloaded)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if err := provider.FileDataSourceProvider.Initialize(inst); err != nil {
+					reloaded <- err
+					return
+				}
+			}
+		}()
+
+		for i := 0; i < 5; i++ {
+			content := fmt.Sprintf("Reloaded %d", i)
+			if err := provider.Update("posts", &Record{Id: "batch.md", Data: map[string]any{"content": content}}, nil); err != nil {
+				t.Fatal(err)
+			} else if _, err := provider.History("posts", "batch.md", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		close(done)
+		if err := <-reloaded; err != nil {
+			t.Fatal(err)
+		}
+
+		if subject := runGit("log", "-1", "refs/heads/content", "--format=%s"); subject != "update(posts): batch.md" {
+			t.Fatalf("Expected update commit, got %s", subject)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		if err := dataSource.Close(); err != nil {
+			t.Fatal(err)
+		} else if _, err := os.Stat(root); !os.IsNotExist(err) {
+			t.Fatalf("Expected the worktree to be removed, got %v", err)
+		}
+
+		// the branch can be checked out again once released
+		runGit("stash", "-q")
+		runGit("checkout", "-q", "content")
+		if content, err := os.ReadFile(filepath.Join(repoDir, "content", "posts", "batch.md")); err != nil || string(content) != "Reloaded 4" {
+			t.Fatalf("Expected the committed record, got %q (%v)", content, err)
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package sulat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// MemoryDataSourceProvider is a data source provider that holds the records
+// in memory. It is mainly used for testing and for ephemeral sites.
+type MemoryDataSourceProvider struct {
+	// FS is the file system where the fixture files are read from
+	FS afero.Fs
+
+	// Collections is a map of collection ids to the paths of their fixture
+	// files. A fixture file is a JSON array of objects with an "id" field.
+	// The collection starts empty if the path is empty.
+	Collections map[string]string
+
+	// Fixtures is a map of collection ids to the records the collections
+	// are seeded with on initialization
+	Fixtures map[string][]*Record
+
+	mu      sync.RWMutex
+	records map[string]map[string]*Record
+}
+
+// MemorySnapshot is a copy of the records of a memory data source provider
+// in a specific point of time
+type MemorySnapshot map[string]map[string]*Record
+
+func injectConfigToMemoryProvider(p *MemoryDataSourceProvider, config map[string]any) {
+	if rawCollections, ok := config["collections"]; ok {
+		if p.Collections == nil {
+			p.Collections = make(map[string]string)
+		}
+
+		if collections, ok := rawCollections.(map[string]string); ok {
+			maps.Copy(p.Collections, collections)
+		} else if collections, ok := rawCollections.(map[string]any); ok {
+			for collectionId, fixturePath := range collections {
+				fixturePath, ok := fixturePath.(string)
+				if !ok {
+					continue
+				}
+				p.Collections[collectionId] = fixturePath
+			}
+		}
+	}
+}
+
+func (p *MemoryDataSourceProvider) Properties() DataSourceProviderProperties {
+	return DataSourceProviderProperties{
+		Id:      "memory",
+		Name:    "Memory",
+		Version: "1.0.0",
+		ConfigSchema: Schema{
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "collections",
+					FieldLabel: "Collections",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "fixtures",
+						FieldLabel: "Fixtures path",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *MemoryDataSourceProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	newProvider := &MemoryDataSourceProvider{
+		FS:       p.FS,
+		Fixtures: p.Fixtures,
+	}
+
+	injectConfigToMemoryProvider(newProvider, config)
+	return newProvider, nil
+}
+
+func (p *MemoryDataSourceProvider) Initialize(i *Instance) error {
+	if p.FS == nil {
+		p.FS = afero.NewOsFs()
+	}
+
+	p.mu.Lock()
+	if p.records == nil {
+		p.records = make(map[string]map[string]*Record)
+	}
+	p.mu.Unlock()
+
+	for collectionId, fixturePath := range p.Collections {
+		records := []*Record{}
+		if len(fixturePath) != 0 {
+			var err error
+			if records, err = p.readFixtures(fixturePath); err != nil {
+				return fmt.Errorf("collection %s: %w", collectionId, err)
+			}
+		}
+
+		if err := p.Seed(collectionId, records...); err != nil {
+			return err
+		}
+	}
+
+	for collectionId, records := range p.Fixtures {
+		if err := p.Seed(collectionId, records...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readFixtures reads the records from a fixture file
+func (p *MemoryDataSourceProvider) readFixtures(fixturePath string) ([]*Record, error) {
+	file, err := p.FS.Open(fixturePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	items := []map[string]any{}
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	if err := decoder.Decode(&items); err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(items))
+	for idx, item := range items {
+		id, ok := item["id"].(string)
+		if !ok || len(id) == 0 {
+			return nil, fmt.Errorf("fixture %d has no id", idx)
+		}
+
+		delete(item, "id")
+		records = append(records, &Record{Id: id, Data: item})
+	}
+	return records, nil
+}
+
+// Seed adds the records to the collection and replaces the existing records
+// with the same id. The collection is created if it does not exist.
+func (p *MemoryDataSourceProvider) Seed(collectionId string, records ...*Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.records == nil {
+		p.records = make(map[string]map[string]*Record)
+	}
+
+	collectionRecords, found := p.records[collectionId]
+	if !found {
+		collectionRecords = make(map[string]*Record)
+		p.records[collectionId] = collectionRecords
+	}
+
+	for _, record := range records {
+		if len(record.Id) == 0 {
+			return NewResponseError(http.StatusBadRequest, "record id is required")
+		}
+		collectionRecords[record.Id] = copyRecord(record)
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the records of all collections
+func (p *MemoryDataSourceProvider) Snapshot() MemorySnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snapshot := make(MemorySnapshot, len(p.records))
+	for collectionId, records := range p.records {
+		snapshot[collectionId] = make(map[string]*Record, len(records))
+		for id, record := range records {
+			snapshot[collectionId][id] = copyRecord(record)
+		}
+	}
+	return snapshot
+}
+
+// RestoreSnapshot replaces the records of all collections with the
+// records of the snapshot
+func (p *MemoryDataSourceProvider) RestoreSnapshot(snapshot MemorySnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records = make(map[string]map[string]*Record, len(snapshot))
+	for collectionId, records := range snapshot {
+		p.records[collectionId] = make(map[string]*Record, len(records))
+		for id, record := range records {
+			p.records[collectionId][id] = copyRecord(record)
+		}
+	}
+}
+
+// copyRecord returns a copy of the record which does not share its data
+// with the original record
+func copyRecord(record *Record) *Record {
+	data, _ := copyValue(record.Data).(map[string]any)
+	return &Record{
+		Id:         record.Id,
+		Data:       data,
+		Codec:      record.Codec,
+		Collection: record.Collection,
+	}
+}
+
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if v == nil {
+			return map[string]any(nil)
+		}
+
+		copied := make(map[string]any, len(v))
+		for key, value := range v {
+			copied[key] = copyValue(value)
+		}
+		return copied
+	case []any:
+		if v == nil {
+			return []any(nil)
+		}
+
+		copied := make([]any, len(v))
+		for i, value := range v {
+			copied[i] = copyValue(value)
+		}
+		return copied
+	}
+	return value
+}
+
+func (p *MemoryDataSourceProvider) Get(collectionId string, id string, opts map[string]any) (*Record, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	records, found := p.records[collectionId]
+	if !found {
+		return nil, NewResponseError(http.StatusNotFound, "collection not found")
+	}
+
+	record, found := records[id]
+	if !found {
+		return nil, NewResponseError(http.StatusNotFound, "record not found")
+	}
+	return copyRecord(record), nil
+}
+
+// matchRecords returns copies of the records matching the query sorted by
+// their ids. The read lock must be held by the caller.
+func (p *MemoryDataSourceProvider) matchRecords(collectionId string, q *query.Query) ([]*Record, error) {
+	records, found := p.records[collectionId]
+	if !found {
+		return nil, NewResponseError(http.StatusNotFound, "collection not found")
+	} else if err := ValidateQuery(q); err != nil {
+		return nil, err
+	}
+
+	ids := maps.Keys(records)
+	slices.Sort(ids)
+
+	matched := []*Record{}
+	for _, id := range ids {
+		if q == nil || q.Match(records[id]) {
+			matched = append(matched, copyRecord(records[id]))
+		}
+	}
+	return matched, nil
+}
+
+func (p *MemoryDataSourceProvider) Find(collectionId string, q *query.Query, opts map[string]any) ([]*Record, error) {
+	p.mu.RLock()
+	found, err := p.matchRecords(collectionId, q)
+	p.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if q != nil && len(found) == 0 {
+		return nil, NewResponseError(http.StatusNotFound, "no records found")
+	}
+
+	return ApplyQueryOptions(q, found)
+}
+
+func (p *MemoryDataSourceProvider) Count(collectionId string, q *query.Query, opts map[string]any) (int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	found, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return 0, err
+	}
+	return len(found), nil
+}
+
+func (p *MemoryDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	records, found := p.records[collectionId]
+	if !found {
+		return NewResponseError(http.StatusNotFound, "collection not found")
+	} else if len(record.Id) == 0 {
+		return NewResponseError(http.StatusBadRequest, "record id is required")
+	} else if _, exists := records[record.Id]; exists {
+		return NewResponseError(http.StatusConflict, "record already exists")
+	}
+
+	records[record.Id] = copyRecord(record)
+	return nil
+}
+
+func (p *MemoryDataSourceProvider) Update(collectionId string, updateRecord *Record, opts map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	records, found := p.records[collectionId]
+	if !found {
+		return NewResponseError(http.StatusNotFound, "collection not found")
+	}
+
+	record, found := records[updateRecord.Id]
+	if !found {
+		return NewResponseError(http.StatusNotFound, "record not found")
+	}
+
+	updatedRecord := copyRecord(updateRecord)
+	if updatedRecord.Collection == nil {
+		updatedRecord.Collection = record.Collection
+	}
+	if updatedRecord.Codec == nil {
+		updatedRecord.Codec = record.Codec
+	}
+
+	records[updateRecord.Id] = updatedRecord
+	return nil
+}
+
+func (p *MemoryDataSourceProvider) Delete(collectionId string, q *query.Query, opts map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	records, found := p.records[collectionId]
+	if !found {
+		return NewResponseError(http.StatusNotFound, "collection not found")
+	} else if q == nil {
+		return NewResponseError(http.StatusBadRequest, "query is required")
+	} else if err := ValidateQuery(q); err != nil {
+		return err
+	}
+
+	maps.DeleteFunc(records, func(id string, record *Record) bool {
+		return q.Match(record)
+	})
+	return nil
+}
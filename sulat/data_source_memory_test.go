@@ -0,0 +1,165 @@
+package sulat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+)
+
+func TestMemoryDataSourceProvider(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "fixtures/authors.json", []byte(`[
+		{"id": "jane", "name": "Jane", "posts": 3},
+		{"id": "john", "name": "John", "posts": 1}
+	]`), 0644)
+
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataSource, err := inst.NewDataSource("memory", "Memory", &MemoryDataSourceProvider{
+		FS: fs,
+		Fixtures: map[string][]*Record{
+			"posts": {
+				{Id: "hello", Data: map[string]any{"title": "Hello", "author": "jane", "tags": []any{"intro"}}},
+				{Id: "second", Data: map[string]any{"title": "Second", "author": "jane", "tags": []any{}}},
+				{Id: "third", Data: map[string]any{"title": "Third", "author": "john", "tags": []any{"intro", "news"}}},
+			},
+		},
+	}, map[string]any{
+		"collections": map[string]any{
+			"authors": "fixtures/authors.json",
+			"pages":   "",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := dataSource.DataSourceProvider.(*MemoryDataSourceProvider)
+
+	findIds := func(t *testing.T, collectionId string, rawQuery string) string {
+		t.Helper()
+		q, err := query.ParseFromString(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := dataSource.Find(collectionId, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]string, len(records))
+		for i, record := range records {
+			ids[i] = record.Id
+		}
+		return strings.Join(ids, ",")
+	}
+
+	t.Run("Seeding", func(t *testing.T) {
+		record, err := dataSource.Get("authors", "jane", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Get("posts") != json.Number("3") {
+			t.Fatalf("Expected posts to be 3, got %v", record.Get("posts"))
+		}
+
+		if ids := findIds(t, "posts", ""); ids != "hello,second,third" {
+			t.Fatalf("Expected seeded posts, got %s", ids)
+		}
+
+		if records, err := dataSource.Find("pages", nil, nil); err != nil {
+			t.Fatal(err)
+		} else if len(records) != 0 {
+			t.Fatalf("Expected no pages, got %d", len(records))
+		}
+
+		_, err = dataSource.Get("comments", "1", nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error for unknown collection, got %v", err)
+		}
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		tests := map[string]string{
+			`contains(tags "intro")`:                      "hello,third",
+			`eq(author "jane", {order:["title","desc"]})`: "second,hello",
+			`or(eq(author "john"),eq(id "hello"))`:        "hello,third",
+			`regex(title "^S")`:                           "second",
+		}
+
+		for rawQuery, expected := range tests {
+			if ids := findIds(t, "posts", rawQuery); ids != expected {
+				t.Errorf("%s: expected %s, got %s", rawQuery, expected, ids)
+			}
+		}
+
+		q, _ := query.ParseFromString(`eq(author "jim")`)
+		_, err := dataSource.Find("posts", q, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		if count, err := provider.Count("posts", q, nil); err != nil || count != 0 {
+			t.Fatalf("Expected count of 0, got %d (%v)", count, err)
+		}
+	})
+
+	t.Run("Records are copied", func(t *testing.T) {
+		record, err := dataSource.Get("posts", "third", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		record.Data["title"] = "Changed"
+		record.Data["tags"].([]any)[0] = "changed"
+
+		if record, _ := dataSource.Get("posts", "third", nil); record.Get("title") != "Third" || record.Get("tags.0") != "intro" {
+			t.Fatalf("Expected stored record to be unchanged, got %v", record.Data)
+		}
+	})
+
+	t.Run("Snapshot and restore", func(t *testing.T) {
+		snapshot := provider.Snapshot()
+
+		err := dataSource.Insert("posts", &Record{Id: "hello", Data: map[string]any{}}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusConflict {
+			t.Fatalf("Expected conflict error, got %v", err)
+		}
+
+		if err := dataSource.Insert("posts", &Record{Id: "fourth", Data: map[string]any{"title": "Fourth"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dataSource.Update("posts", &Record{Id: "hello", Data: map[string]any{"title": "Hi", "author": "jane"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		err = dataSource.Update("posts", &Record{Id: "missing"}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		if err := dataSource.Delete("posts", query.Eq("author", "jane"), nil); err != nil {
+			t.Fatal(err)
+		} else if ids := findIds(t, "posts", ""); ids != "fourth,third" {
+			t.Fatalf("Expected fourth and third to remain, got %s", ids)
+		}
+
+		if err := dataSource.Delete("posts", nil, nil); err == nil {
+			t.Fatal("Expected error for deleting without a query")
+		}
+
+		provider.RestoreSnapshot(snapshot)
+		if ids := findIds(t, "posts", ""); ids != "hello,second,third" {
+			t.Fatalf("Expected restored posts, got %s", ids)
+		} else if record, _ := dataSource.Get("posts", "hello", nil); record.Get("title") != "Hello" {
+			t.Fatalf("Expected restored title, got %v", record.Get("title"))
+		}
+	})
+}
@@ -0,0 +1,629 @@
+package sulat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"golang.org/x/exp/maps"
+)
+
+const (
+	RESTPaginationNone   = "none"
+	RESTPaginationPage   = "page"
+	RESTPaginationOffset = "offset"
+
+	RESTFilterSimple  = "simple"
+	RESTFilterBracket = "bracket"
+
+	defaultRESTPageSize = 100
+
+	// maxRESTPages limits the pages fetched in a single find in case
+	// the API does not honor the pagination parameters
+	maxRESTPages = 1000
+)
+
+// RESTDataSourceProvider is a data source provider that maps the operations
+// on the records into requests to a JSON API. Queries are partially
+// translated into query string filters and the fetched records are always
+// matched in memory.
+type RESTDataSourceProvider struct {
+	// Client is the HTTP client used for the requests
+	Client *http.Client
+
+	// BaseURL is the URL where the endpoints are resolved from
+	BaseURL string
+
+	// Collections is a map of collection ids to endpoints. The collection
+	// id is used as the endpoint if the endpoint is empty.
+	Collections map[string]string
+
+	// AuthHeaderName and AuthHeader are the name and value of the header
+	// sent with every request. AuthHeaderName defaults to Authorization.
+	AuthHeaderName string
+	AuthHeader     string
+
+	// IdField is the field of the items containing the record id.
+	// Defaults to "id".
+	IdField string
+
+	// ItemsField is the field of the list response containing the items.
+	// The response itself or its "data" field is used if empty.
+	ItemsField string
+
+	// Pagination is the pagination style of the list endpoints
+	// ("none", "page" or "offset")
+	Pagination string
+
+	// PageSize is the number of items requested per page
+	PageSize int
+
+	// FilterStyle is the style of the query string filters. Equality
+	// conditions are sent as field=value with the "simple" style while the
+	// "bracket" style additionally sends comparisons as field[op]=value.
+	FilterStyle string
+
+	// UpdateMethod is the HTTP method used for updating records
+	// (PUT or PATCH)
+	UpdateMethod string
+}
+
+func injectConfigToRESTProvider(p *RESTDataSourceProvider, config map[string]any) {
+	for key, dest := range map[string]*string{
+		"base_url":         &p.BaseURL,
+		"auth_header_name": &p.AuthHeaderName,
+		"auth_header":      &p.AuthHeader,
+		"id_field":         &p.IdField,
+		"items_field":      &p.ItemsField,
+		"pagination":       &p.Pagination,
+		"filter_style":     &p.FilterStyle,
+		"update_method":    &p.UpdateMethod,
+	} {
+		if rawValue, ok := config[key]; ok {
+			if value, ok := rawValue.(string); ok {
+				*dest = value
+			}
+		}
+	}
+
+	if rawPageSize, ok := config["page_size"]; ok {
+		switch v := rawPageSize.(type) {
+		case int:
+			p.PageSize = v
+		case int64:
+			p.PageSize = int(v)
+		case float64:
+			p.PageSize = int(v)
+		case json.Number:
+			pageSize, _ := v.Int64()
+			p.PageSize = int(pageSize)
+		}
+	}
+
+	if rawCollections, ok := config["collections"]; ok {
+		if p.Collections == nil {
+			p.Collections = make(map[string]string)
+		}
+
+		if collections, ok := rawCollections.(map[string]string); ok {
+			maps.Copy(p.Collections, collections)
+		} else if collections, ok := rawCollections.(map[string]any); ok {
+			for collectionId, endpoint := range collections {
+				endpoint, ok := endpoint.(string)
+				if !ok {
+					continue
+				}
+				p.Collections[collectionId] = endpoint
+			}
+		}
+	}
+}
+
+func (p *RESTDataSourceProvider) Properties() DataSourceProviderProperties {
+	return DataSourceProviderProperties{
+		Id:      "rest",
+		Name:    "REST API",
+		Version: "1.0.0",
+		ConfigSchema: Schema{
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "base_url",
+					FieldLabel: "Base URL",
+					Required:   true,
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "collections",
+					FieldLabel: "Collections",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "endpoint",
+						FieldLabel: "Endpoint",
+					},
+				},
+			},
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "auth_header_name",
+					FieldLabel: "Auth header name",
+				},
+			},
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "auth_header",
+					FieldLabel: "Auth header value",
+				},
+			},
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "id_field",
+					FieldLabel: "ID field",
+				},
+			},
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "items_field",
+					FieldLabel: "Items field",
+				},
+			},
+			SelectSchemaField{
+				BaseField: BaseField{
+					FieldName:  "pagination",
+					FieldLabel: "Pagination style",
+				},
+				Options: []string{RESTPaginationNone, RESTPaginationPage, RESTPaginationOffset},
+				Max:     1,
+			},
+			NumberSchemaField{
+				BaseField: BaseField{
+					FieldName:  "page_size",
+					FieldLabel: "Page size",
+				},
+				Max: maxRESTPages,
+			},
+			SelectSchemaField{
+				BaseField: BaseField{
+					FieldName:  "filter_style",
+					FieldLabel: "Filter style",
+				},
+				Options: []string{RESTFilterSimple, RESTFilterBracket},
+				Max:     1,
+			},
+			SelectSchemaField{
+				BaseField: BaseField{
+					FieldName:  "update_method",
+					FieldLabel: "Update method",
+				},
+				Options: []string{http.MethodPut, http.MethodPatch},
+				Max:     1,
+			},
+		},
+	}
+}
+
+func (p *RESTDataSourceProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	newProvider := &RESTDataSourceProvider{
+		Client:         p.Client,
+		AuthHeaderName: "Authorization",
+		IdField:        "id",
+		Pagination:     RESTPaginationNone,
+		PageSize:       defaultRESTPageSize,
+		FilterStyle:    RESTFilterSimple,
+		UpdateMethod:   http.MethodPut,
+	}
+
+	injectConfigToRESTProvider(newProvider, config)
+
+	if len(newProvider.BaseURL) == 0 {
+		return nil, errors.New("no base url specified")
+	} else if _, err := url.Parse(newProvider.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	} else if newProvider.PageSize <= 0 {
+		newProvider.PageSize = defaultRESTPageSize
+	}
+
+	return newProvider, nil
+}
+
+func (p *RESTDataSourceProvider) Initialize(i *Instance) error {
+	if p.Client == nil {
+		p.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return nil
+}
+
+// endpoint returns the URL of the collection endpoint with the
+// path segments appended
+func (p *RESTDataSourceProvider) endpoint(collectionId string, segments ...string) (*url.URL, error) {
+	endpoint, found := p.Collections[collectionId]
+	if !found {
+		return nil, NewResponseError(http.StatusNotFound, "collection not found")
+	} else if len(endpoint) == 0 {
+		endpoint = collectionId
+	}
+
+	for _, segment := range segments {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(segment)
+	}
+
+	baseURL, err := url.Parse(strings.TrimSuffix(p.BaseURL, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	return baseURL.Parse(strings.TrimPrefix(endpoint, "/"))
+}
+
+// request sends the request and decodes the JSON response into dest
+func (p *RESTDataSourceProvider) request(method string, endpoint *url.URL, body any, dest any) error {
+	var bodyReader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, endpoint.String(), bodyReader)
+	if err != nil {
+		return err
+	}
+
+	p.setHeaders(req, body != nil)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return NewResponseError(http.StatusBadGateway, err.Error())
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return NewResponseError(http.StatusNotFound, "record not found")
+	case resp.StatusCode == http.StatusConflict:
+		return NewResponseError(http.StatusConflict, "record already exists")
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return NewResponseError(http.StatusBadGateway, fmt.Sprintf("%s %s: unexpected status %d", method, endpoint.Path, resp.StatusCode))
+	case dest == nil || resp.StatusCode == http.StatusNoContent:
+		return nil
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(dest); err != nil && !errors.Is(err, io.EOF) {
+		return NewResponseError(http.StatusBadGateway, fmt.Sprintf("%s %s: invalid response: %s", method, endpoint.Path, err))
+	}
+	return nil
+}
+
+// setHeaders sets the content type and authentication headers of the request
+func (p *RESTDataSourceProvider) setHeaders(req *http.Request, hasBody bool) {
+	req.Header.Set("Accept", "application/json")
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(p.AuthHeader) != 0 {
+		req.Header.Set(p.AuthHeaderName, p.AuthHeader)
+	}
+}
+
+// Ping checks whether the API can be reached. Responses other than server
+// errors are considered reachable since the base URL of most APIs is not
+// an endpoint and may require authentication.
+func (p *RESTDataSourceProvider) Ping() error {
+	req, err := http.NewRequest(http.MethodGet, p.BaseURL, nil)
+	if err != nil {
+		return err
+	}
+	p.setHeaders(req, false)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return NewResponseError(http.StatusBadGateway, err.Error())
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return NewResponseError(http.StatusBadGateway, fmt.Sprintf("GET %s: unexpected status %d", req.URL.Path, resp.StatusCode))
+	}
+	return nil
+}
+
+// Close closes the idle connections of the HTTP client
+func (p *RESTDataSourceProvider) Close() error {
+	if p.Client != nil {
+		p.Client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Status reports whether the API can be reached
+func (p *RESTDataSourceProvider) Status() DataSourceStatus {
+	return newDataSourceStatus(p.Ping(), map[string]any{
+		"base_url": p.BaseURL,
+	})
+}
+
+// toRecord converts an item of the API into a record
+func (p *RESTDataSourceProvider) toRecord(item map[string]any) (*Record, error) {
+	var id string
+	switch v := item[p.IdField].(type) {
+	case string:
+		id = v
+	case json.Number:
+		id = v.String()
+	case nil:
+		return nil, NewResponseError(http.StatusBadGateway, fmt.Sprintf("item has no %s field", p.IdField))
+	default:
+		id = fmt.Sprint(v)
+	}
+
+	return &Record{Id: id, Data: item}, nil
+}
+
+// extractItems returns the items of a list response
+func (p *RESTDataSourceProvider) extractItems(response any) ([]map[string]any, error) {
+	if object, ok := response.(map[string]any); ok {
+		if len(p.ItemsField) != 0 {
+			response = object[p.ItemsField]
+		} else {
+			response = object["data"]
+		}
+	}
+
+	rawItems, ok := response.([]any)
+	if !ok {
+		return nil, NewResponseError(http.StatusBadGateway, "list response does not contain items")
+	}
+
+	items := make([]map[string]any, 0, len(rawItems))
+	for _, rawItem := range rawItems {
+		item, ok := rawItem.(map[string]any)
+		if !ok {
+			return nil, NewResponseError(http.StatusBadGateway, "list response contains an invalid item")
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// queryFilters translates the conditions of the query supported by the
+// filter style into query string parameters. Only a single condition
+// or the conditions of a top-level and query are translated.
+func (p *RESTDataSourceProvider) queryFilters(q *query.Query) url.Values {
+	filters := url.Values{}
+	if q == nil || len(q.Operator) == 0 {
+		return filters
+	}
+
+	conditions := []*query.Query{q}
+	if q.Operator == query.OpAnd {
+		conditions, _ = q.Value.([]*query.Query)
+	}
+
+	for _, condition := range conditions {
+		value, ok := restFilterValue(condition.Value)
+		if !ok || len(condition.Field) == 0 {
+			continue
+		}
+
+		switch {
+		case condition.Operator == query.OpEq:
+			filters.Add(condition.Field, value)
+		case p.FilterStyle == RESTFilterBracket && condition.Operator.IsComparative():
+			filters.Add(condition.Field+"["+string(condition.Operator)+"]", value)
+		}
+	}
+	return filters
+}
+
+func restFilterValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int, int64, float64:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// fetchRecords fetches all of the records of the collection matching
+// the filters following the pagination style
+func (p *RESTDataSourceProvider) fetchRecords(collectionId string, filters url.Values) ([]*Record, error) {
+	endpoint, err := p.endpoint(collectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*Record{}
+	for page := 0; page < maxRESTPages; page++ {
+		params := endpoint.Query()
+		for key, values := range filters {
+			params[key] = values
+		}
+
+		switch p.Pagination {
+		case RESTPaginationPage:
+			params.Set("page", strconv.Itoa(page+1))
+			params.Set("per_page", strconv.Itoa(p.PageSize))
+		case RESTPaginationOffset:
+			params.Set("offset", strconv.Itoa(page*p.PageSize))
+			params.Set("limit", strconv.Itoa(p.PageSize))
+		}
+
+		pageEndpoint := *endpoint
+		pageEndpoint.RawQuery = params.Encode()
+
+		var response any
+		if err := p.request(http.MethodGet, &pageEndpoint, nil, &response); err != nil {
+			if rErr, ok := err.(*ResponseError); ok && rErr.StatusCode == http.StatusNotFound {
+				return nil, NewResponseError(http.StatusBadGateway, fmt.Sprintf("GET %s: endpoint not found", endpoint.Path))
+			}
+			return nil, err
+		}
+
+		items, err := p.extractItems(response)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			record, err := p.toRecord(item)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+
+		// a short page is the last page while a page larger than
+		// requested means that the pagination is ignored by the API
+		if p.Pagination != RESTPaginationPage && p.Pagination != RESTPaginationOffset {
+			break
+		} else if len(items) != p.PageSize {
+			break
+		}
+	}
+
+	return records, nil
+}
+
+func (p *RESTDataSourceProvider) matchRecords(collectionId string, q *query.Query) ([]*Record, error) {
+	if err := ValidateQuery(q); err != nil {
+		return nil, err
+	}
+
+	records, err := p.fetchRecords(collectionId, p.queryFilters(q))
+	if err != nil {
+		return nil, err
+	}
+
+	// the API may ignore some of the filters so the records are
+	// always matched against the query
+	found := []*Record{}
+	for _, record := range records {
+		if q == nil || q.Match(record) {
+			found = append(found, record)
+		}
+	}
+	return found, nil
+}
+
+func (p *RESTDataSourceProvider) Get(collectionId string, id string, opts map[string]any) (*Record, error) {
+	endpoint, err := p.endpoint(collectionId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	item := map[string]any{}
+	if err := p.request(http.MethodGet, endpoint, nil, &item); err != nil {
+		return nil, err
+	}
+
+	record, err := p.toRecord(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// rely on the requested id if the API returns a different form of it
+	record.Id = id
+	return record, nil
+}
+
+func (p *RESTDataSourceProvider) Find(collectionId string, q *query.Query, opts map[string]any) ([]*Record, error) {
+	found, err := p.matchRecords(collectionId, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if q != nil && len(found) == 0 {
+		return nil, NewResponseError(http.StatusNotFound, "no records found")
+	}
+
+	return ApplyQueryOptions(q, found)
+}
+
+// payload returns the body sent when creating or updating the record
+func (p *RESTDataSourceProvider) payload(record *Record) map[string]any {
+	payload := maps.Clone(record.Data)
+	if payload == nil {
+		payload = map[string]any{}
+	}
+
+	if _, hasId := payload[p.IdField]; !hasId && len(record.Id) != 0 {
+		payload[p.IdField] = record.Id
+	}
+	return payload
+}
+
+func (p *RESTDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
+	endpoint, err := p.endpoint(collectionId)
+	if err != nil {
+		return err
+	}
+
+	created := map[string]any{}
+	if err := p.request(http.MethodPost, endpoint, p.payload(record), &created); err != nil {
+		return err
+	}
+
+	// adopt the id assigned by the API
+	if len(record.Id) == 0 && len(created) != 0 {
+		if createdRecord, err := p.toRecord(created); err == nil {
+			record.Id = createdRecord.Id
+		}
+	}
+	return nil
+}
+
+func (p *RESTDataSourceProvider) Update(collectionId string, record *Record, opts map[string]any) error {
+	endpoint, err := p.endpoint(collectionId, record.Id)
+	if err != nil {
+		return err
+	}
+
+	return p.request(p.UpdateMethod, endpoint, p.payload(record), nil)
+}
+
+func (p *RESTDataSourceProvider) Delete(collectionId string, q *query.Query, opts map[string]any) error {
+	if q == nil {
+		return NewResponseError(http.StatusBadRequest, "query is required")
+	}
+
+	found, err := p.matchRecords(collectionId, q.WithoutPagination())
+	if err != nil {
+		return err
+	}
+
+	for _, record := range found {
+		endpoint, err := p.endpoint(collectionId, record.Id)
+		if err != nil {
+			return err
+		}
+
+		if err := p.request(http.MethodDelete, endpoint, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
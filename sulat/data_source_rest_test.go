@@ -0,0 +1,258 @@
+package sulat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+func TestRESTDataSourceProvider(t *testing.T) {
+	var mu sync.Mutex
+	items := map[string]map[string]any{
+		"1": {"id": 1, "title": "First", "category": "news", "views": 10},
+		"2": {"id": 2, "title": "Second", "category": "blog", "views": 25},
+		"3": {"id": 3, "title": "Third", "category": "news", "views": 40},
+		"4": {"id": 4, "title": "Fourth", "category": "blog", "views": 5},
+		"5": {"id": 5, "title": "Fifth", "category": "news", "views": 15},
+	}
+	requests := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		id, isItem := strings.CutPrefix(r.URL.Path, "/api/articles/")
+		if !isItem && r.URL.Path != "/api/articles" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case !isItem && r.Method == http.MethodGet:
+			ids := []string{}
+			for id, item := range items {
+				if category := r.URL.Query().Get("category"); category != "" && item["category"] != category {
+					continue
+				}
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+			start, end := min((page-1)*perPage, len(ids)), min(page*perPage, len(ids))
+
+			pageItems := []any{}
+			for _, id := range ids[start:end] {
+				pageItems = append(pageItems, items[id])
+			}
+			json.NewEncoder(w).Encode(map[string]any{"results": pageItems, "total": len(ids)})
+		case !isItem && r.Method == http.MethodPost:
+			item := map[string]any{}
+			json.NewDecoder(r.Body).Decode(&item)
+			if _, hasId := item["id"]; !hasId {
+				item["id"] = strconv.Itoa(len(items) + 1)
+			}
+
+			id := item["id"].(string)
+			if _, exists := items[id]; exists {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			items[id] = item
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(item)
+		case items[id] == nil:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(items[id])
+		case r.Method == http.MethodPatch:
+			item := items[id]
+			json.NewDecoder(r.Body).Decode(&item)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			delete(items, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataSource, err := inst.NewDataSource("api", "API", &RESTDataSourceProvider{}, map[string]any{
+		"base_url": server.URL + "/api",
+		"collections": map[string]any{
+			"articles": "",
+			"authors":  "/people",
+		},
+		"auth_header_name": "X-Api-Key",
+		"auth_header":      "secret",
+		"items_field":      "results",
+		"pagination":       RESTPaginationPage,
+		"page_size":        2,
+		"update_method":    http.MethodPatch,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resetRequests := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = requests[:0]
+	}
+
+	findIds := func(t *testing.T, rawQuery string) []string {
+		t.Helper()
+		q, err := query.ParseFromString(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := dataSource.Find("articles", q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]string, len(records))
+		for i, record := range records {
+			ids[i] = record.Id
+		}
+		return ids
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		record, err := dataSource.Get("articles", "3", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Id != "3" || record.Get("title") != "Third" {
+			t.Fatalf("Unexpected record: %+v", record)
+		}
+
+		_, err = dataSource.Get("articles", "99", nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		_, err = dataSource.Find("authors", nil, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusBadGateway {
+			t.Fatalf("Expected bad gateway error for missing endpoint, got %v", err)
+		}
+	})
+
+	t.Run("Find with pagination and filters", func(t *testing.T) {
+		resetRequests()
+		if ids := findIds(t, `{order:["id"]}`); strings.Join(ids, ",") != "1,2,3,4,5" {
+			t.Fatalf("Expected all articles, got %v", ids)
+		} else if len(requests) != 3 {
+			t.Fatalf("Expected 3 page requests, got %v", requests)
+		}
+
+		resetRequests()
+		ids := findIds(t, `and(eq(category "news"),gt(views 12),{order:["views","desc"]})`)
+		if strings.Join(ids, ",") != "3,5" {
+			t.Fatalf("Expected articles 3 and 5, got %v", ids)
+		} else if requests[0] != "GET /api/articles?category=news&page=1&per_page=2" {
+			t.Fatalf("Expected the equality filter to be sent, got %v", requests)
+		}
+
+		q, _ := query.ParseFromString(`eq(category "sports")`)
+		_, err := dataSource.Find("articles", q, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("Insert, update and delete", func(t *testing.T) {
+		err := dataSource.Insert("articles", &Record{Id: "1", Data: map[string]any{"title": "Duplicate"}}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusConflict {
+			t.Fatalf("Expected conflict error, got %v", err)
+		}
+
+		record := &Record{Data: map[string]any{"title": "Sixth", "category": "blog", "views": 0}}
+		if err := dataSource.Insert("articles", record, nil); err != nil {
+			t.Fatal(err)
+		} else if record.Id != "6" {
+			t.Fatalf("Expected the id assigned by the API, got %q", record.Id)
+		}
+
+		resetRequests()
+		if err := dataSource.Update("articles", &Record{Id: "6", Data: map[string]any{"views": 3}}, nil); err != nil {
+			t.Fatal(err)
+		} else if requests[0] != "PATCH /api/articles/6" {
+			t.Fatalf("Expected a PATCH request, got %v", requests)
+		}
+
+		if record, err := dataSource.Get("articles", "6", nil); err != nil {
+			t.Fatal(err)
+		} else if record.Get("views") != json.Number("3") || record.Get("title") != "Sixth" {
+			t.Fatalf("Expected the record to be updated, got %v", record.Data)
+		}
+
+		q, _ := query.ParseFromString(`and(eq(category "blog"),lt(views 10))`)
+		if err := dataSource.Delete("articles", q, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if ids := findIds(t, `{order:["id"]}`); strings.Join(ids, ",") != "1,2,3,5" {
+			t.Fatalf("Expected articles 4 and 6 to be deleted, got %v", ids)
+		}
+
+		if err := dataSource.Delete("articles", nil, nil); err == nil {
+			t.Fatal("Expected error for deleting without a query")
+		}
+	})
+
+	t.Run("Authentication", func(t *testing.T) {
+		provider := dataSource.DataSourceProvider.(*RESTDataSourceProvider)
+		unauthorized := *provider
+		unauthorized.AuthHeader = "wrong"
+
+		_, err := unauthorized.Get("articles", "1", nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusBadGateway {
+			t.Fatalf("Expected bad gateway error, got %v", err)
+		}
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		if status := dataSource.Status(); !status.Healthy {
+			t.Fatalf("Expected healthy status, got %+v", status)
+		}
+
+		// the API is reachable even if the credentials are rejected
+		unauthorized := *dataSource.DataSourceProvider.(*RESTDataSourceProvider)
+		unauthorized.AuthHeader = "wrong"
+		if err := unauthorized.Ping(); err != nil {
+			t.Fatalf("Expected unauthorized response to be reachable, got %v", err)
+		}
+
+		unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer unavailable.Close()
+
+		down := unauthorized
+		down.BaseURL = unavailable.URL
+		if err := down.Ping(); err == nil {
+			t.Fatal("Expected error for server error response")
+		}
+	})
+}
@@ -0,0 +1,438 @@
+package sulat
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedpals/sulatcms/sulat/query"
+	"golang.org/x/exp/maps"
+)
+
+// SQLiteDataSourceProvider is a data source provider that stores each collection
+// as a table of JSON documents in an SQLite database. Queries are translated
+// into SQL whenever possible and are matched in memory otherwise.
+type SQLiteDataSourceProvider struct {
+	// Path is the path of the database file
+	Path string
+
+	// Collections is a map of collection ids to table names. The collection
+	// id is used as the table name if the table name is empty.
+	Collections map[string]string
+
+	db *sqlx.DB
+}
+
+func injectConfigToSQLiteProvider(p *SQLiteDataSourceProvider, config map[string]any) {
+	if rawPath, ok := config["path"]; ok {
+		if path, ok := rawPath.(string); ok {
+			p.Path = path
+		}
+	}
+
+	if rawCollections, ok := config["collections"]; ok {
+		if p.Collections == nil {
+			p.Collections = make(map[string]string)
+		}
+
+		if collections, ok := rawCollections.(map[string]string); ok {
+			maps.Copy(p.Collections, collections)
+		} else if collections, ok := rawCollections.(map[string]any); ok {
+			for collectionId, table := range collections {
+				table, ok := table.(string)
+				if !ok {
+					continue
+				}
+				p.Collections[collectionId] = table
+			}
+		}
+	}
+}
+
+func (p *SQLiteDataSourceProvider) Properties() DataSourceProviderProperties {
+	return DataSourceProviderProperties{
+		Id:      "sqlite",
+		Name:    "SQLite",
+		Version: "1.0.0",
+		ConfigSchema: Schema{
+			StringSchemaField{
+				BaseField: BaseField{
+					FieldName:  "path",
+					FieldLabel: "Database path",
+					Required:   true,
+				},
+			},
+			KVGroupSchemaField{
+				BaseField: BaseField{
+					FieldName:  "collections",
+					FieldLabel: "Collections",
+				},
+				KeySchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "collection_id",
+						FieldLabel: "Collection ID",
+						Required:   true,
+					},
+				},
+				ValueSchema: StringSchemaField{
+					BaseField: BaseField{
+						FieldName:  "table",
+						FieldLabel: "Table name",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *SQLiteDataSourceProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	newProvider := &SQLiteDataSourceProvider{}
+	injectConfigToSQLiteProvider(newProvider, config)
+
+	if len(newProvider.Path) == 0 {
+		return nil, errors.New("no database path specified")
+	}
+	return newProvider, nil
+}
+
+func (p *SQLiteDataSourceProvider) Initialize(i *Instance) error {
+	if p.db == nil {
+		db, err := sqlx.Open("sqlite", p.Path)
+		if err != nil {
+			return err
+		}
+
+		if p.Path == ":memory:" {
+			// each connection of an in-memory database has its own database
+			db.SetMaxOpenConns(1)
+		}
+		p.db = db
+	}
+
+	for collectionId := range p.Collections {
+		table, _ := p.table(collectionId)
+		if _, err := p.db.Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, data TEXT NOT NULL DEFAULT '{}')",
+			table,
+		)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ping checks whether the database can be reached
+func (p *SQLiteDataSourceProvider) Ping() error {
+	if p.db == nil {
+		return NewResponseError(http.StatusServiceUnavailable, "database is not open")
+	}
+	return p.db.Ping()
+}
+
+// Close closes the database
+func (p *SQLiteDataSourceProvider) Close() error {
+	if p.db == nil {
+		return nil
+	}
+
+	err := p.db.Close()
+	p.db = nil
+	return err
+}
+
+// Status reports the health of the database
+func (p *SQLiteDataSourceProvider) Status() DataSourceStatus {
+	details := map[string]any{
+		"path":        p.Path,
+		"collections": len(p.Collections),
+	}
+
+	if p.db != nil {
+		stats := p.db.Stats()
+		details["open_connections"] = stats.OpenConnections
+	}
+	return newDataSourceStatus(p.Ping(), details)
+}
+
+// table returns the quoted table name of the collection
+func (p *SQLiteDataSourceProvider) table(collectionId string) (string, error) {
+	table, found := p.Collections[collectionId]
+	if !found {
+		return "", NewResponseError(http.StatusNotFound, "collection not found")
+	} else if len(table) == 0 {
+		table = collectionId
+	}
+	return `"` + strings.ReplaceAll(table, `"`, `""`) + `"`, nil
+}
+
+func (p *SQLiteDataSourceProvider) scanRecords(rows *sqlx.Rows) ([]*Record, error) {
+	defer rows.Close()
+
+	records := []*Record{}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+
+		record, err := decodeSQLiteRecord(id, data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func decodeSQLiteRecord(id string, data string) (*Record, error) {
+	record := &Record{Id: id}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record.Data); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func encodeSQLiteRecord(record *Record) (string, error) {
+	data := record.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func (p *SQLiteDataSourceProvider) Get(collectionId string, id string, opts map[string]any) (*Record, error) {
+	table, err := p.table(collectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var data string
+	err = p.db.Get(&data, fmt.Sprintf("SELECT data FROM %s WHERE id = ?", table), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, NewResponseError(http.StatusNotFound, "record not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeSQLiteRecord(id, data)
+}
+
+// matchRecords returns the records matching the query in memory for
+// queries which cannot be translated into SQL
+func (p *SQLiteDataSourceProvider) matchRecords(table string, where string, args []any, q *query.Query) ([]*Record, error) {
+	rows, err := p.db.Queryx(fmt.Sprintf("SELECT id, data FROM %s WHERE %s", table, where), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := p.scanRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []*Record{}
+	for _, record := range records {
+		if q == nil || q.Match(record) {
+			found = append(found, record)
+		}
+	}
+	return found, nil
+}
+
+// narrowingWhere translates the conditions of the query which can be
+// compiled into SQL in order to narrow down the records to be matched in
+// memory. Only the conditions of a top-level and query are considered.
+func narrowingWhere(q *query.Query) (string, []any) {
+	where, args, err := (&query.SQLCompiler{}).CompileWhere(q)
+	if err == nil {
+		return where, args
+	} else if q.Operator != query.OpAnd {
+		return "1", nil
+	}
+
+	clauses := []string{}
+	args = []any{}
+	queries, _ := q.Value.([]*query.Query)
+	for _, subQuery := range queries {
+		subWhere, subArgs, err := (&query.SQLCompiler{}).CompileWhere(subQuery)
+		if err != nil {
+			continue
+		}
+		clauses = append(clauses, "("+subWhere+")")
+		args = append(args, subArgs...)
+	}
+
+	if len(clauses) == 0 {
+		return "1", nil
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+func (p *SQLiteDataSourceProvider) Find(collectionId string, q *query.Query, opts map[string]any) ([]*Record, error) {
+	table, err := p.table(collectionId)
+	if err != nil {
+		return nil, err
+	} else if err := ValidateQuery(q); err != nil {
+		return nil, err
+	}
+
+	compiler := &query.SQLCompiler{}
+	compiled, err := compiler.Compile(q)
+	if err == nil {
+		rows, err := p.db.Queryx(fmt.Sprintf("SELECT id, data FROM %s%s", table, compiled.String()), compiled.Args...)
+		if err != nil {
+			return nil, err
+		}
+
+		records, err := p.scanRecords(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if q != nil && len(records) == 0 {
+			// an empty page does not mean that nothing has matched
+			if count, err := p.Count(collectionId, q, opts); err != nil {
+				return nil, err
+			} else if count == 0 {
+				return nil, NewResponseError(http.StatusNotFound, "no records found")
+			}
+		}
+		return ProjectRecords(q, records), nil
+	} else if !errors.Is(err, query.ErrNotCompilable) {
+		return nil, NewResponseError(http.StatusBadRequest, err.Error())
+	}
+
+	where, args := narrowingWhere(q)
+	found, err := p.matchRecords(table, where, args, q)
+	if err != nil {
+		return nil, err
+	} else if q != nil && len(found) == 0 {
+		return nil, NewResponseError(http.StatusNotFound, "no records found")
+	}
+
+	return ApplyQueryOptions(q, found)
+}
+
+func (p *SQLiteDataSourceProvider) Count(collectionId string, q *query.Query, opts map[string]any) (int, error) {
+	table, err := p.table(collectionId)
+	if err != nil {
+		return 0, err
+	} else if err := ValidateQuery(q); err != nil {
+		return 0, err
+	}
+
+	where, args, err := (&query.SQLCompiler{}).CompileWhere(q)
+	if err != nil {
+		where, args := narrowingWhere(q)
+		found, err := p.matchRecords(table, where, args, q)
+		if err != nil {
+			return 0, err
+		}
+		return len(found), nil
+	}
+
+	var count int
+	if err := p.db.Get(&count, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, where), args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (p *SQLiteDataSourceProvider) Insert(collectionId string, record *Record, opts map[string]any) error {
+	table, err := p.table(collectionId)
+	if err != nil {
+		return err
+	} else if len(record.Id) == 0 {
+		return NewResponseError(http.StatusBadRequest, "record id is required")
+	}
+
+	data, err := encodeSQLiteRecord(record)
+	if err != nil {
+		return err
+	}
+
+	result, err := p.db.Exec(fmt.Sprintf("INSERT INTO %s (id, data) VALUES (?, ?) ON CONFLICT (id) DO NOTHING", table), record.Id, data)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return NewResponseError(http.StatusConflict, "record already exists")
+	}
+	return nil
+}
+
+func (p *SQLiteDataSourceProvider) Update(collectionId string, record *Record, opts map[string]any) error {
+	table, err := p.table(collectionId)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeSQLiteRecord(record)
+	if err != nil {
+		return err
+	}
+
+	result, err := p.db.Exec(fmt.Sprintf("UPDATE %s SET data = ? WHERE id = ?", table), data, record.Id)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return NewResponseError(http.StatusNotFound, "record not found")
+	}
+	return nil
+}
+
+func (p *SQLiteDataSourceProvider) Delete(collectionId string, q *query.Query, opts map[string]any) error {
+	table, err := p.table(collectionId)
+	if err != nil {
+		return err
+	} else if q == nil {
+		return NewResponseError(http.StatusBadRequest, "query is required")
+	} else if err := ValidateQuery(q); err != nil {
+		return err
+	}
+
+	where, args, err := (&query.SQLCompiler{}).CompileWhere(q)
+	if err == nil {
+		_, err := p.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), args...)
+		return err
+	}
+
+	where, args = narrowingWhere(q)
+	found, err := p.matchRecords(table, where, args, q)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, record := range found {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), record.Id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,186 @@
+package sulat
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+func TestSQLiteDataSourceProvider(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataSource, err := inst.NewDataSource("catalog", "Catalog", &SQLiteDataSourceProvider{}, map[string]any{
+		"path": ":memory:",
+		"collections": map[string]any{
+			"products": "",
+			"orders":   "shop_orders",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products := []*Record{
+		{Id: "apple", Data: map[string]any{"name": "Apple", "price": 1.5, "tags": []any{"fruit", "red"}}},
+		{Id: "banana", Data: map[string]any{"name": "Banana", "price": 0.5, "tags": []any{"fruit"}}},
+		{Id: "carrot", Data: map[string]any{"name": "Carrot", "price": 0.75, "tags": []any{"vegetable"}}},
+		{Id: "durian", Data: map[string]any{"name": "Durian", "price": 12, "tags": []any{"fruit", "exotic"}}},
+	}
+
+	for _, record := range products {
+		if err := dataSource.Insert("products", record, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	findIds := func(t *testing.T, rawQuery string) []string {
+		t.Helper()
+		q, err := query.ParseFromString(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := dataSource.Find("products", q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]string, len(records))
+		for i, record := range records {
+			ids[i] = record.Id
+		}
+		return ids
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		record, err := dataSource.Get("products", "durian", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Get("price") != json.Number("12") {
+			t.Fatalf("Expected price to be 12, got %v", record.Get("price"))
+		}
+
+		_, err = dataSource.Get("products", "eggplant", nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		_, err = dataSource.Get("customers", "jane", nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error for unknown collection, got %v", err)
+		}
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{`contains(tags "fruit", {order:["price","desc"]})`, []string{"durian", "apple", "banana"}},
+			{`lt(price 1, {order:["name"], limit:1, offset:1})`, []string{"carrot"}},
+			// regex cannot be compiled into SQL and is matched in memory
+			{`and(contains(tags "fruit"),regex(name "^[AB]"),{order:["id","desc"]})`, []string{"banana", "apple"}},
+			{`or(regex(name "n$"),eq(id "apple"))`, []string{"apple", "durian"}},
+		}
+
+		for _, tt := range tests {
+			ids := findIds(t, tt.query)
+			if len(ids) != len(tt.expected) {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.expected, ids)
+				continue
+			}
+
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Errorf("%s: expected %v, got %v", tt.query, tt.expected, ids)
+					break
+				}
+			}
+		}
+
+		q, _ := query.ParseFromString(`eq(name "Eggplant")`)
+		_, err := dataSource.Find("products", q, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		q, _ = query.ParseFromString(`gt(price 0, {limit:2, offset:10})`)
+		if records, err := dataSource.Find("products", q, nil); err != nil {
+			t.Fatalf("Expected an empty page, got %v", err)
+		} else if len(records) != 0 {
+			t.Fatalf("Expected an empty page, got %d records", len(records))
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		counter := dataSource.DataSourceProvider.(DataSourceCounter)
+		for rawQuery, expected := range map[string]int{
+			`contains(tags "fruit", {limit:1})`: 3,
+			`regex(name "^[CD]")`:               2,
+		} {
+			q, _ := query.ParseFromString(rawQuery)
+			if count, err := counter.Count("products", q, nil); err != nil {
+				t.Fatal(err)
+			} else if count != expected {
+				t.Errorf("%s: expected %d, got %d", rawQuery, expected, count)
+			}
+		}
+	})
+
+	t.Run("Insert, update and delete", func(t *testing.T) {
+		err := dataSource.Insert("products", &Record{Id: "apple"}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusConflict {
+			t.Fatalf("Expected conflict error, got %v", err)
+		}
+
+		if err := dataSource.Update("products", &Record{Id: "apple", Data: map[string]any{"name": "Green Apple", "price": 2}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		record, err := dataSource.Get("products", "apple", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Get("name") != "Green Apple" {
+			t.Fatalf("Expected the record to be updated, got %v", record.Data)
+		}
+
+		err = dataSource.Update("products", &Record{Id: "eggplant"}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected not found error, got %v", err)
+		}
+
+		for _, rawQuery := range []string{`eq(id "carrot")`, `regex(name "^Dur")`} {
+			q, _ := query.ParseFromString(rawQuery)
+			if err := dataSource.Delete("products", q, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if ids := findIds(t, `{order:["id"]}`); len(ids) != 2 || ids[0] != "apple" || ids[1] != "banana" {
+			t.Fatalf("Expected apple and banana to remain, got %v", ids)
+		}
+
+		if err := dataSource.Delete("products", nil, nil); err == nil {
+			t.Fatal("Expected error for deleting without a query")
+		}
+	})
+
+	t.Run("Custom table name", func(t *testing.T) {
+		if err := dataSource.Insert("orders", &Record{Id: "order-1", Data: map[string]any{"total": 3}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		provider := dataSource.DataSourceProvider.(*SQLiteDataSourceProvider)
+		var count int
+		if err := provider.db.Get(&count, "SELECT COUNT(*) FROM shop_orders"); err != nil {
+			t.Fatal(err)
+		} else if count != 1 {
+			t.Fatalf("Expected 1 order, got %d", count)
+		}
+	})
+}
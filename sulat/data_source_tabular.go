@@ -0,0 +1,561 @@
+package sulat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// csvNumberPattern matches the cells which are decoded as numbers. Only
+// the cells written in the same way they are encoded are matched so the
+// untouched cells are written back as is.
+var csvNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// tabularFile is a CSV or JSON array file where each row is a record
+type tabularFile struct {
+	// Path is the path of the file relative to the root
+	Path string
+
+	// IdColumn is the column containing the record ids
+	IdColumn string
+
+	// Columns is the list of columns in the order they appear in the file
+	Columns []string
+
+	// Ids is the list of record ids in the order of the rows
+	Ids []string
+
+	// envelopeKeys and envelope are the keys and values of the object
+	// wrapping the rows of JSON files in its "data" field
+	envelopeKeys []string
+	envelope     map[string]json.RawMessage
+}
+
+// isCSV checks if the rows are stored as CSV
+func (t *tabularFile) isCSV() bool {
+	return strings.EqualFold(filepath.Ext(t.Path), ".csv")
+}
+
+// addColumns appends the columns of the row which are not part of the table
+func (t *tabularFile) addColumns(row map[string]any) {
+	newColumns := []string{}
+	for column := range row {
+		if !slices.Contains(t.Columns, column) {
+			newColumns = append(newColumns, column)
+		}
+	}
+
+	slices.Sort(newColumns)
+	t.Columns = append(t.Columns, newColumns...)
+}
+
+// rowId returns the id of the row from its id column
+func (t *tabularFile) rowId(row map[string]any) (string, error) {
+	switch v := row[t.IdColumn].(type) {
+	case string:
+		if len(v) != 0 {
+			return v, nil
+		}
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("%s: row has no %s column", t.Path, t.IdColumn)
+}
+
+func decodeCell(cell string) any {
+	switch {
+	case cell == "true":
+		return true
+	case cell == "false":
+		return false
+	case csvNumberPattern.MatchString(cell):
+		return json.Number(cell)
+	}
+	return cell
+}
+
+func encodeCell(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+
+	// nested values are stored as JSON
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// readOrderedObject decodes a JSON object while keeping the order of its keys
+func readOrderedObject(data []byte) ([]string, map[string]json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	} else if token != json.Delim('{') {
+		return nil, nil, errors.New("value is not an object")
+	}
+
+	keys := []string{}
+	values := map[string]json.RawMessage{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
+
+// writeOrderedObject encodes the values as a JSON object with the keys in order
+func writeOrderedObject(buf *bytes.Buffer, keys []string, values map[string]any) error {
+	buf.WriteByte('{')
+	written := 0
+	for _, key := range keys {
+		value, exists := values[key]
+		if !exists {
+			continue
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		if written > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+		written++
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// read reads the rows of the file
+func (t *tabularFile) read(r io.Reader) ([]map[string]any, error) {
+	if t.isCSV() {
+		return t.readCSV(r)
+	}
+	return t.readJSON(r)
+}
+
+func (t *tabularFile) readCSV(r io.Reader) ([]map[string]any, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		t.Columns = []string{t.IdColumn}
+		return []map[string]any{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	t.Columns = header
+	if !slices.Contains(t.Columns, t.IdColumn) {
+		return nil, fmt.Errorf("%s: id column %s not found", t.Path, t.IdColumn)
+	}
+
+	rows := []map[string]any{}
+	for {
+		cells, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(cells))
+		for i, cell := range cells {
+			row[header[i]] = decodeCell(cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (t *tabularFile) readJSON(r io.Reader) ([]map[string]any, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		t.Columns = []string{t.IdColumn}
+		return []map[string]any{}, nil
+	} else if data[0] == '{' {
+		// rows wrapped in an object (eg. {"data": [...]})
+		t.envelopeKeys, t.envelope, err = readOrderedObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Path, err)
+		}
+		data = t.envelope["data"]
+	}
+
+	rawRows := []json.RawMessage{}
+	if err := json.Unmarshal(data, &rawRows); err != nil {
+		return nil, fmt.Errorf("%s: rows must be an array of objects", t.Path)
+	}
+
+	t.Columns = []string{}
+	rows := make([]map[string]any, 0, len(rawRows))
+	for _, rawRow := range rawRows {
+		keys, values, err := readOrderedObject(rawRow)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Path, err)
+		}
+
+		row := make(map[string]any, len(keys))
+		for _, key := range keys {
+			decoder := json.NewDecoder(bytes.NewReader(values[key]))
+			decoder.UseNumber()
+
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			row[key] = value
+
+			if !slices.Contains(t.Columns, key) {
+				t.Columns = append(t.Columns, key)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if !slices.Contains(t.Columns, t.IdColumn) {
+		t.Columns = append([]string{t.IdColumn}, t.Columns...)
+	}
+	return rows, nil
+}
+
+// write encodes the rows in the order of the ids
+func (t *tabularFile) write(records map[string]*Record) ([]byte, error) {
+	rows := make([]map[string]any, 0, len(t.Ids))
+	for _, id := range t.Ids {
+		if record, found := records[id]; found {
+			rows = append(rows, record.Data)
+		}
+	}
+
+	if t.isCSV() {
+		return t.writeCSV(rows)
+	}
+	return t.writeJSON(rows)
+}
+
+func (t *tabularFile) writeCSV(rows []map[string]any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	if err := writer.Write(t.Columns); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		cells := make([]string, len(t.Columns))
+		for i, column := range t.Columns {
+			cell, err := encodeCell(row[column])
+			if err != nil {
+				return nil, err
+			}
+			cells[i] = cell
+		}
+
+		if err := writer.Write(cells); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func (t *tabularFile) writeJSON(rows []map[string]any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('[')
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeOrderedObject(buf, t.Columns, row); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte(']')
+
+	if t.envelope != nil {
+		envelope := make(map[string]any, len(t.envelope))
+		for key, value := range t.envelope {
+			envelope[key] = value
+		}
+		envelope["data"] = json.RawMessage(buf.Bytes())
+
+		buf = &bytes.Buffer{}
+		if err := writeOrderedObject(buf, t.envelopeKeys, envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+// importTable reads the records of a tabular collection. A missing file is
+// treated as an empty table which is created once a record is inserted.
+func (p *FileDataSourceProvider) importTable(collection *Collection, tablePath string) (map[string]*Record, error) {
+	idColumn := p.TableIdColumns[collection.Id]
+	if len(idColumn) == 0 {
+		idColumn = "id"
+	}
+
+	table := &tabularFile{Path: tablePath, IdColumn: idColumn}
+	if ext := strings.ToLower(filepath.Ext(tablePath)); ext != ".csv" && ext != ".json" {
+		return nil, fmt.Errorf("%s: tables must be CSV or JSON files", tablePath)
+	}
+
+	if p.tables == nil {
+		p.tables = make(map[string]*tabularFile)
+	}
+
+	records := map[string]*Record{}
+	file, err := p.FS.Open(filepath.Join(p.Root, tablePath))
+	if errors.Is(err, os.ErrNotExist) {
+		table.Columns = []string{idColumn}
+		p.tables[collection.Id] = table
+		return records, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := table.read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		id, err := table.rowId(row)
+		if err != nil {
+			return nil, err
+		} else if _, exists := records[id]; exists {
+			return nil, fmt.Errorf("%s: duplicate id %s", tablePath, id)
+		}
+
+		table.Ids = append(table.Ids, id)
+		records[id] = &Record{
+			Id:         id,
+			Collection: collection,
+			Data:       row,
+		}
+	}
+
+	p.tables[collection.Id] = table
+	return records, nil
+}
+
+// saveTable rewrites the file of the tabular collection
+func (p *FileDataSourceProvider) saveTable(collectionId string) error {
+	table := p.tables[collectionId]
+	data, err := table.write(p.records[collectionId])
+	if err != nil {
+		return err
+	}
+
+	return p.writeFile(table.Path, data)
+}
+
+// tableRow returns the row of the record stored in the table
+func (p *FileDataSourceProvider) tableRow(table *tabularFile, record *Record) map[string]any {
+	row := maps.Clone(record.Data)
+	if row == nil {
+		row = map[string]any{}
+	}
+
+	row[table.IdColumn] = record.Id
+	table.addColumns(row)
+	return row
+}
+
+// putTableRow inserts or replaces the row of the record and rewrites
+// the table. The lock must be held by the caller.
+func (p *FileDataSourceProvider) putTableRow(collectionId string, record *Record) error {
+	table := p.tables[collectionId]
+	records := p.records[collectionId]
+
+	previous, exists := records[record.Id]
+	if !exists {
+		table.Ids = append(table.Ids, record.Id)
+	}
+
+	collection := record.Collection
+	if collection == nil {
+		collection = p.cachedCollections[collectionId]
+	}
+
+	records[record.Id] = &Record{
+		Id:         record.Id,
+		Collection: collection,
+		Data:       p.tableRow(table, record),
+	}
+
+	if err := p.saveTable(collectionId); err != nil {
+		// revert the changes made in memory
+		if exists {
+			records[record.Id] = previous
+		} else {
+			delete(records, record.Id)
+			table.Ids = table.Ids[:len(table.Ids)-1]
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteTableRows removes the rows of the records and rewrites the table.
+// The lock must be held by the caller.
+func (p *FileDataSourceProvider) deleteTableRows(collectionId string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	table := p.tables[collectionId]
+	records := p.records[collectionId]
+
+	removed := make(map[string]*Record, len(ids))
+	for _, id := range ids {
+		removed[id] = records[id]
+		delete(records, id)
+	}
+
+	previousIds := table.Ids
+	table.Ids = slices.DeleteFunc(slices.Clone(table.Ids), func(id string) bool {
+		_, found := removed[id]
+		return found
+	})
+
+	if err := p.saveTable(collectionId); err != nil {
+		maps.Copy(records, removed)
+		table.Ids = previousIds
+		return err
+	}
+	return nil
+}
+
+// recordPath returns the path of the file where the record is stored
+func (p *FileDataSourceProvider) recordPath(collectionId string, key string) string {
+	if table, isTable := p.tables[collectionId]; isTable {
+		return table.Path
+	}
+	return key
+}
+
+// InferSchema infers the schema of a tabular collection from its columns
+// and the values of its rows
+func (p *FileDataSourceProvider) InferSchema(collectionId string) (Schema, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	table, isTable := p.tables[collectionId]
+	if !isTable {
+		if _, found := p.records[collectionId]; !found {
+			return nil, NewResponseError(http.StatusNotFound, "collection not found")
+		}
+		return nil, NewResponseError(http.StatusNotImplemented, "schema can only be inferred from tabular collections")
+	}
+
+	records := p.records[collectionId]
+	schema := make(Schema, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		isNumber, isDecimal, isBoolean, hasValues := true, false, true, false
+		for _, record := range records {
+			switch v := record.Data[column].(type) {
+			case nil:
+				continue
+			case string:
+				if len(v) == 0 {
+					continue
+				}
+				isNumber, isBoolean = false, false
+			case json.Number:
+				isBoolean = false
+				isDecimal = isDecimal || strings.ContainsAny(v.String(), ".eE")
+			case float64:
+				isBoolean = false
+				isDecimal = isDecimal || v != math.Trunc(v)
+			case int, int64:
+				isBoolean = false
+			case bool:
+				isNumber = false
+			default:
+				isNumber, isBoolean = false, false
+			}
+			hasValues = true
+		}
+
+		field := BaseField{
+			FieldName:  column,
+			FieldLabel: column,
+			Required:   column == table.IdColumn,
+		}
+
+		switch {
+		case hasValues && isNumber && column != table.IdColumn:
+			schema = append(schema, NumberSchemaField{
+				BaseField: field,
+				Min:       math.MinInt,
+				Max:       math.MaxInt,
+				IsDecimal: isDecimal,
+			})
+		case hasValues && isBoolean:
+			schema = append(schema, BooleanSchemaField{BaseField: field})
+		default:
+			schema = append(schema, StringSchemaField{BaseField: field})
+		}
+	}
+	return schema, nil
+}
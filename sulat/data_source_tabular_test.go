@@ -0,0 +1,169 @@
+package sulat
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+)
+
+func TestTabularCollections(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "site/data/prices.csv", []byte(
+		"sku,name,price,in_stock,notes\n"+
+			"A1,Apple,1.50,true,\"crisp, sweet\"\n"+
+			"B2,Banana,0.25,false,\n"+
+			"C3,Cherry,12,true,seasonal\n",
+	), 0644)
+	afero.WriteFile(fs, "site/data/socials.json", []byte(`{
+  "updated": "2024-01-01",
+  "data": [
+    {"name": "Facebook", "id": "facebook", "url": "https://facebook.com"},
+    {"name": "Mastodon", "id": "mastodon", "url": "https://mastodon.social"}
+  ]
+}
+`), 0644)
+
+	dataSource, err := inst.NewDataSource("site", "Site", &FileDataSourceProvider{FS: fs}, map[string]any{
+		"root": "site",
+		"tables": map[string]any{
+			"prices":  "data/prices.csv",
+			"socials": "data/socials.json",
+			"links":   "data/links.csv",
+		},
+		"table_id_columns": map[string]any{
+			"prices": "sku",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := dataSource.DataSourceProvider.(*FileDataSourceProvider)
+
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+		content, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	t.Run("CSV rows", func(t *testing.T) {
+		record, err := dataSource.Get("prices", "A1", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Get("name") != "Apple" || record.Get("price") != json.Number("1.50") || record.Get("in_stock") != true {
+			t.Fatalf("Unexpected record: %v", record.Data)
+		}
+
+		q, _ := query.ParseFromString(`and(gt(price 1),eq(in_stock true),{order:["price","desc"]})`)
+		records, err := dataSource.Find("prices", q, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(records) != 2 || records[0].Id != "C3" || records[1].Id != "A1" {
+			t.Fatalf("Expected C3 and A1, got %v", records)
+		}
+
+		if err := dataSource.Update("prices", &Record{Id: "B2", Data: map[string]any{"name": "Banana", "price": 0.3, "in_stock": true, "origin": "PH"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dataSource.Insert("prices", &Record{Id: "D4", Data: map[string]any{"name": "Durian", "price": 8}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		err = dataSource.Insert("prices", &Record{Id: "A1", Data: map[string]any{"name": "Avocado"}}, nil)
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusConflict {
+			t.Fatalf("Expected conflict error, got %v", err)
+		}
+
+		if err := dataSource.Delete("prices", query.Eq("name", "Cherry"), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "sku,name,price,in_stock,notes,origin\n" +
+			"A1,Apple,1.50,true,\"crisp, sweet\",\n" +
+			"B2,Banana,0.3,true,,PH\n" +
+			"D4,Durian,8,,,\n"
+		if content := readFile(t, "site/data/prices.csv"); content != expected {
+			t.Fatalf("Expected file:\n%s\ngot:\n%s", expected, content)
+		}
+	})
+
+	t.Run("JSON array rows", func(t *testing.T) {
+		if err := dataSource.Update("socials", &Record{Id: "mastodon", Data: map[string]any{"url": "https://fosstodon.org", "name": "Mastodon"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `{
+  "updated": "2024-01-01",
+  "data": [
+    {
+      "name": "Facebook",
+      "id": "facebook",
+      "url": "https://facebook.com"
+    },
+    {
+      "name": "Mastodon",
+      "id": "mastodon",
+      "url": "https://fosstodon.org"
+    }
+  ]
+}
+`
+		if content := readFile(t, "site/data/socials.json"); content != expected {
+			t.Fatalf("Expected file:\n%s\ngot:\n%s", expected, content)
+		}
+	})
+
+	t.Run("Missing tables are created", func(t *testing.T) {
+		if records, err := dataSource.Find("links", nil, nil); err != nil || len(records) != 0 {
+			t.Fatalf("Expected an empty collection, got %v (%v)", records, err)
+		}
+
+		if err := dataSource.Insert("links", &Record{Id: "home", Data: map[string]any{"url": "/"}}, nil); err != nil {
+			t.Fatal(err)
+		} else if content := readFile(t, "site/data/links.csv"); content != "id,url\nhome,/\n" {
+			t.Fatalf("Unexpected file: %q", content)
+		}
+	})
+
+	t.Run("Infer schema", func(t *testing.T) {
+		schema, err := provider.InferSchema("prices")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{
+			"sku":      "string",
+			"name":     "string",
+			"price":    "number",
+			"in_stock": "boolean",
+			"notes":    "string",
+			"origin":   "string",
+		}
+		if len(schema) != len(expected) {
+			t.Fatalf("Expected %d fields, got %d", len(expected), len(schema))
+		}
+
+		for i, name := range []string{"sku", "name", "price", "in_stock", "notes", "origin"} {
+			if schema[i].Name() != name || schema[i].Type() != expected[name] {
+				t.Errorf("Expected field %d to be %s (%s), got %s (%s)", i, name, expected[name], schema[i].Name(), schema[i].Type())
+			}
+		}
+
+		if price := schema[2].(NumberSchemaField); !price.IsDecimal {
+			t.Error("Expected price to be decimal")
+		} else if valid, err := price.Validate(json.Number("100.5")); !valid {
+			t.Errorf("Expected price to be valid, got %v", err)
+		}
+	})
+}
@@ -1,7 +1,11 @@
 package sulat
 
 import (
+	"errors"
 	"io"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -40,13 +44,16 @@ posts = "project/posts/*.md"
 	var provider DataSourceProvider = &FileDataSourceProvider{FS: testFs}
 
 	t.Run("Simple", func(t *testing.T) {
-		dataSource := inst.NewDataSource("sample", "Sample", provider, map[string]any{
+		dataSource, err := inst.NewDataSource("sample", "Sample", provider, map[string]any{
 			"root": "project",
 			"collections": map[string]string{
 				"data":  "data/*.json",
 				"posts": "posts/*.md",
 			},
 		})
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		// Sample find
 		records, err := dataSource.Find("posts", query.Eq("id", "hello-world.md"), nil)
@@ -158,10 +165,258 @@ posts = "project/posts/*.md"
 		}
 	})
 
+	t.Run("With query options", func(t *testing.T) {
+		dataSource, err := inst.NewDataSource("sample", "Sample", provider, map[string]any{
+			"root": "project",
+			"collections": map[string]string{
+				"posts": "posts/*.md",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []string{"c.md", "a.md", "b.md"} {
+			if err := dataSource.Insert("posts", &Record{Id: id, Data: map[string]any{"content": id}}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		q, err := query.ParseFromString(`in(id ["a.md","b.md","c.md"], {order:["id","desc"], limit:2, offset:1})`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := dataSource.Find("posts", q, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(records) != 2 {
+			t.Fatalf("Expected 2 records, got %d", len(records))
+		} else if records[0].Id != "b.md" || records[1].Id != "a.md" {
+			t.Fatalf("Expected records 'b.md' and 'a.md', got %s and %s", records[0].Id, records[1].Id)
+		}
+
+		projected, err := dataSource.Find("posts", q.WithOption("select", []any{"title"}), nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, hasContent := projected[0].Data["content"]; hasContent {
+			t.Fatal("Expected content to be excluded from the projected record")
+		} else if _, hasContent := records[0].Data["content"]; !hasContent {
+			t.Fatal("Expected projection not to modify the stored record")
+		}
+
+		count, err := dataSource.DataSourceProvider.(DataSourceCounter).Count("posts", q, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if count != 3 {
+			t.Fatalf("Expected count to be 3, got %d", count)
+		}
+	})
+
+	t.Run("With path templates", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		dataSource, err := inst.NewDataSource("blog", "Blog", &FileDataSourceProvider{FS: fs}, map[string]any{
+			"root": "blog",
+			"collections": map[string]any{
+				"posts": "content/posts/*/*.md",
+				"pages": "content/pages/*.md",
+			},
+			"paths": map[string]any{
+				"posts": "content/posts/{{date:2006}}/{{slug}}.md",
+			},
+			"slug_fields": map[string]any{
+				"posts": "headline",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = dataSource.Insert("posts", &Record{
+			Data: map[string]any{"headline": "Hello, World!", "date": "2023-12-31", "content": "Hello!"},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := fs.Stat("blog/content/posts/2023/hello-world.md"); err != nil {
+			t.Fatal(err)
+		}
+
+		// records without path templates are stored in the collection directory
+		if err := dataSource.Insert("pages", &Record{Id: "about.md", Data: map[string]any{"content": "About"}}, nil); err != nil {
+			t.Fatal(err)
+		} else if _, err := fs.Stat("blog/content/pages/about.md"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dataSource.Insert("pages", &Record{Id: "about.json", Data: map[string]any{}}, nil); err == nil {
+			t.Fatal("Expected records outside the collection path to be rejected")
+		}
+
+		// changing the fields of the path moves the record
+		record := &Record{
+			Id:   "hello-world.md",
+			Data: map[string]any{"headline": "Hello Again", "date": "2024-01-01", "content": "Hello!"},
+		}
+		if err := dataSource.Update("posts", record, nil); err != nil {
+			t.Fatal(err)
+		} else if record.Id != "hello-again.md" {
+			t.Fatalf("Expected id to be 'hello-again.md', got %s", record.Id)
+		} else if _, err := fs.Stat("blog/content/posts/2024/hello-again.md"); err != nil {
+			t.Fatal(err)
+		} else if _, err := fs.Stat("blog/content/posts/2023/hello-world.md"); err == nil {
+			t.Fatal("Expected the old file to be removed")
+		}
+
+		if _, err := dataSource.Get("posts", "hello-again.md", nil); err != nil {
+			t.Fatal(err)
+		} else if _, err := dataSource.Get("posts", "hello-world.md", nil); err == nil {
+			t.Fatal("Expected the old record to be removed")
+		}
+	})
+
+	t.Run("With recursive globs and ignore files", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		for filename, content := range map[string]string{
+			"docs/index.md":            "Index",
+			"docs/guides/setup.md":     "Setup",
+			"docs/guides/_drafts/a.md": "Draft",
+			"docs/build/index.md":      "Build output",
+			"notes/todo.md":            "Todo",
+			"notes/private/secret.md":  "Secret",
+			".gitignore":               "build/\n",
+			".sulatignore":             "private/\n",
+			"site/sulat.toml":          "[collections]\ndocs = \"docs/**/*.md\"\n",
+		} {
+			afero.WriteFile(fs, filename, []byte(content), 0644)
+		}
+
+		dataSource, err := inst.NewDataSource("docs", "Docs", &FileDataSourceProvider{FS: fs}, map[string]any{
+			"root": ".",
+			"collections": map[string]any{
+				"docs": "docs/**/*.md",
+			},
+			"includes": map[string]any{
+				"docs": []any{"notes/**/*.md"},
+			},
+			"excludes": map[string]any{
+				"docs": []any{"**/_drafts/**"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := dataSource.Find("docs", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []string{}
+		for _, record := range records {
+			ids = append(ids, record.Id)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, []string{"index.md", "setup.md", "todo.md"}) {
+			t.Fatalf("Expected index.md, setup.md and todo.md, got %v", ids)
+		}
+
+		if err := dataSource.Insert("docs", &Record{Id: "a.md", Data: map[string]any{"content": "A"}}, nil); err != nil {
+			t.Fatal(err)
+		} else if exists, _ := afero.Exists(fs, "docs/a.md"); !exists {
+			t.Fatal("Expected the record to be saved in the collection directory")
+		}
+
+		// config files in nested directories are found
+		provider, err := (&FileDataSourceProvider{FS: fs}).WithConfig(map[string]any{"root": "."})
+		if err != nil {
+			t.Fatal(err)
+		} else if configPath := provider.(*FileDataSourceProvider).ConfigPath; configPath != filepath.Join("site", "sulat.toml") {
+			t.Fatalf("Expected config path to be site/sulat.toml, got %s", configPath)
+		}
+	})
+
+	t.Run("With path-relative ids", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "site/posts/2023/intro.json", []byte(`{"slug":"intro-2023","title":"2023"}`), 0644)
+		afero.WriteFile(fs, "site/posts/2024/intro.json", []byte(`{"slug":"intro-2024","title":"2024"}`), 0644)
+
+		newDataSource := func(format string) *FileDataSourceProvider {
+			provider, err := (&FileDataSourceProvider{FS: fs}).WithConfig(map[string]any{
+				"root":        "site",
+				"collections": map[string]any{"posts": "posts/**/*.json"},
+				"id_formats":  map[string]any{"posts": format},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fsProvider := provider.(*FileDataSourceProvider)
+			if err := fsProvider.Initialize(inst); err != nil {
+				t.Fatal(err)
+			}
+			return fsProvider
+		}
+
+		for format, expected := range map[string][]string{
+			FileIdPath:           {"posts/2023/intro.json", "posts/2024/intro.json"},
+			FileIdPathWithoutExt: {"posts/2023/intro", "posts/2024/intro"},
+			"field:slug":         {"intro-2023", "intro-2024"},
+		} {
+			provider := newDataSource(format)
+			for i, id := range expected {
+				record, err := provider.Get("posts", id, nil)
+				if err != nil {
+					t.Fatalf("%s: %v", format, err)
+				} else if title := record.Get("title"); title != []string{"2023", "2024"}[i] {
+					t.Fatalf("%s: expected title of %s to be %s, got %v", format, id, []string{"2023", "2024"}[i], title)
+				}
+			}
+		}
+
+		// duplicate ids are reported when importing the records
+		err := (&FileDataSourceProvider{
+			FS:          fs,
+			Root:        "site",
+			Collections: map[string]string{"posts": "posts/**/*.json"},
+		}).Initialize(inst)
+		if err == nil || !strings.Contains(err.Error(), `have the same record id "intro.json"`) {
+			t.Fatalf("Expected duplicate id error, got %v", err)
+		}
+
+		provider := newDataSource(FileIdPathWithoutExt)
+		record := &Record{Id: "posts/2025/intro", Data: map[string]any{"slug": "intro-2025", "title": "2025"}}
+		if err := provider.Insert("posts", record, nil); err != nil {
+			t.Fatal(err)
+		} else if exists, _ := afero.Exists(fs, "site/posts/2025/intro.json"); !exists {
+			t.Fatal("Expected the record to be saved in its path")
+		}
+
+		if err := provider.Insert("posts", &Record{Id: "../outside", Data: map[string]any{}}, nil); err == nil {
+			t.Fatal("Expected records outside the collection to be rejected")
+		}
+
+		// ids taken from fields change along with the field
+		provider = newDataSource("field:slug")
+		record = &Record{Id: "intro-2023", Data: map[string]any{"slug": "hello-2023", "title": "2023"}}
+		if err := provider.Update("posts", record, nil); err != nil {
+			t.Fatal(err)
+		} else if record.Id != "hello-2023" {
+			t.Fatalf("Expected id to be 'hello-2023', got %s", record.Id)
+		}
+
+		record = &Record{Id: "hello-2023", Data: map[string]any{"slug": "intro-2024", "title": "2023"}}
+		if err := provider.Update("posts", record, nil); err == nil {
+			t.Fatal("Expected conflict error")
+		}
+	})
+
 	t.Run("With config file", func(t *testing.T) {
-		dataSource := inst.NewDataSource("sample", "Sample", provider, map[string]any{
+		dataSource, err := inst.NewDataSource("sample", "Sample", provider, map[string]any{
 			"config_path": "sulat.toml",
 		})
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		// Sample find
 		records, err := dataSource.Find("posts", query.Eq("id", "hello-world.md"), nil)
@@ -172,3 +427,167 @@ posts = "project/posts/*.md"
 		}
 	})
 }
+
+func TestDataSourceStatus(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.RegisterDataSourceProvider(&SQLiteDataSourceProvider{})
+	inst.RegisterDataSourceProvider(&FileDataSourceProvider{})
+
+	t.Run("Import errors", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "site/posts/2023/intro.md", []byte("2023"), 0644)
+		afero.WriteFile(fs, "site/posts/2024/intro.md", []byte("2024"), 0644)
+
+		dataSource, err := inst.NewDataSource("posts", "Posts", &FileDataSourceProvider{FS: fs}, map[string]any{
+			"root":        "site",
+			"collections": map[string]any{"posts": "posts/**/*.md"},
+		})
+		if err == nil {
+			t.Fatal("Expected duplicate id error")
+		} else if registered, err := inst.FindDataSource("posts"); err != nil || registered != dataSource {
+			t.Fatalf("Expected the data source to be registered, got %v", err)
+		}
+
+		status := dataSource.Status()
+		if status.Healthy || len(status.Errors) != 1 || !strings.Contains(status.Errors[0], "intro.md") {
+			t.Fatalf("Expected unhealthy status with the import error, got %+v", status)
+		} else if counts := status.Details["records"].(map[string]any); counts["posts"] != 1 {
+			t.Fatalf("Expected 1 imported record, got %v", counts["posts"])
+		}
+	})
+
+	t.Run("Missing root", func(t *testing.T) {
+		dataSource, err := inst.NewDataSource("missing", "Missing", &FileDataSourceProvider{FS: afero.NewMemMapFs()}, map[string]any{
+			"root":        "missing",
+			"collections": map[string]any{"posts": "posts/*.md"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if status := dataSource.Status(); status.Healthy || len(status.Message) == 0 {
+			t.Fatalf("Expected unhealthy status, got %+v", status)
+		}
+	})
+
+	t.Run("Reinitialize on update", func(t *testing.T) {
+		dataSource, err := inst.CreateDataSource(DataSource{
+			Id:         "catalog",
+			Name:       "Catalog",
+			ProviderId: "sqlite",
+			Config: DataSourceConfig{
+				"path":        ":memory:",
+				"collections": map[string]any{"products": ""},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if status := dataSource.Status(); !status.Healthy {
+			t.Fatalf("Expected healthy status, got %+v", status)
+		}
+
+		if err := dataSource.Insert("products", &Record{Id: "a", Data: map[string]any{"name": "A"}}, nil); err != nil {
+			t.Fatal(err)
+		} else if _, err := dataSource.Find("orders", nil, nil); err == nil {
+			t.Fatal("Expected orders to not exist yet")
+		}
+
+		dataSource.Config = DataSourceConfig{
+			"path":        ":memory:",
+			"collections": map[string]any{"products": "", "orders": ""},
+		}
+		if err := inst.UpdateDataSource(dataSource); err != nil {
+			t.Fatal(err)
+		} else if _, err := dataSource.Find("orders", nil, nil); err != nil {
+			t.Fatal(err)
+		} else if status := dataSource.Status(); !status.Healthy {
+			t.Fatalf("Expected healthy status, got %+v", status)
+		}
+
+		// the config is persisted
+		var config DataSourceConfig
+		if err := inst.db.Get(&config, "SELECT config FROM data_sources WHERE id = ?", "catalog"); err != nil {
+			t.Fatal(err)
+		} else if collections := config["collections"].(map[string]any); len(collections) != 2 {
+			t.Fatalf("Expected 2 collections, got %v", collections)
+		}
+
+		dataSource.ProviderId = "unknown"
+		if err := inst.UpdateDataSource(dataSource); err == nil {
+			t.Fatal("Expected unknown provider error")
+		}
+		dataSource.ProviderId = "sqlite"
+
+		provider := dataSource.DataSourceProvider.(*SQLiteDataSourceProvider)
+		if err := inst.RemoveDataSource("catalog"); err != nil {
+			t.Fatal(err)
+		} else if err := provider.Ping(); err == nil {
+			t.Fatal("Expected the database to be closed")
+		}
+	})
+
+	t.Run("Reinitialize errors", func(t *testing.T) {
+		dataSource, err := inst.CreateDataSource(DataSource{
+			Id:         "pages",
+			Name:       "Pages",
+			ProviderId: "fs",
+			Config:     DataSourceConfig{"root": t.TempDir(), "collections": map[string]any{"pages": "*.md"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// configs accepted by the schema may still fail to initialize
+		dataSource.Config = DataSourceConfig{
+			"root":        t.TempDir(),
+			"collections": map[string]any{"pages": "*.md"},
+			"id_formats":  map[string]any{"pages": "unknown"},
+		}
+		if err := inst.UpdateDataSource(dataSource); err == nil {
+			t.Fatal("Expected the initialization error to be returned")
+		} else if status := dataSource.Status(); status.Healthy {
+			t.Fatalf("Expected unhealthy status, got %+v", status)
+		}
+
+		closing, err := inst.NewDataSource("closing", "Closing", &closeErrorProvider{
+			MemoryDataSourceProvider: &MemoryDataSourceProvider{},
+			closeErr:                 errors.New("still in use"),
+		}, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := closing.Reinitialize(); err == nil {
+			t.Fatal("Expected the close error to be returned")
+		} else if status := closing.Status(); status.Healthy || !slices.Contains(status.Errors, "still in use") {
+			t.Fatalf("Expected the close error to be reported, got %+v", status)
+		}
+	})
+}
+
+// closeErrorProvider is a memory data source provider which fails to close
+type closeErrorProvider struct {
+	*MemoryDataSourceProvider
+	closeErr error
+}
+
+func (p *closeErrorProvider) WithConfig(config map[string]any) (DataSourceProvider, error) {
+	provider, err := p.MemoryDataSourceProvider.WithConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &closeErrorProvider{provider.(*MemoryDataSourceProvider), p.closeErr}, nil
+}
+
+func (p *closeErrorProvider) Ping() error {
+	return nil
+}
+
+func (p *closeErrorProvider) Close() error {
+	return p.closeErr
+}
+
+func (p *closeErrorProvider) Status() DataSourceStatus {
+	return newDataSourceStatus(nil, nil)
+}
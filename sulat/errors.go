@@ -3,6 +3,7 @@ package sulat
 type ResponseError struct {
 	StatusCode int
 	Message    string
+	Details    any
 }
 
 func (err *ResponseError) Error() string {
@@ -15,3 +16,13 @@ func NewResponseError(statusCode int, message string) *ResponseError {
 		Message:    message,
 	}
 }
+
+// NewResponseErrorWithDetails creates a response error with additional
+// details (eg. a list of validation errors) to be included in the response
+func NewResponseErrorWithDetails(statusCode int, message string, details any) *ResponseError {
+	return &ResponseError{
+		StatusCode: statusCode,
+		Message:    message,
+		Details:    details,
+	}
+}
@@ -0,0 +1,205 @@
+package sulat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
+)
+
+// defaultIgnoreFiles are the ignore files read from the root of the file
+// system data source if none are specified
+var defaultIgnoreFiles = []string{".gitignore", ".sulatignore"}
+
+// ignoreRule is a pattern of an ignore file converted to a glob
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreList is the list of rules from the ignore files. Similar to
+// .gitignore, the last matching rule decides whether a path is ignored.
+type ignoreList []ignoreRule
+
+// parseIgnoreFile parses the patterns of a .gitignore-style file. Patterns
+// are relative to the directory where the ignore file is located.
+func parseIgnoreFile(content []byte) ignoreList {
+	rules := ignoreList{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		// patterns without a slash match at any depth
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		if len(line) == 0 || !doublestar.ValidatePattern(line) {
+			continue
+		}
+
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored reports whether the slash-separated path is ignored without
+// checking its parent directories
+func (l ignoreList) ignored(name string, isDir bool) bool {
+	ignored := false
+	for _, rule := range l {
+		if rule.dirOnly && !isDir {
+			continue
+		} else if matched, _ := doublestar.Match(rule.pattern, name); matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// pathMatcher matches the slash-separated paths of the files of a
+// collection relative to the root
+type pathMatcher struct {
+	includes []string
+	excludes []string
+	ignores  ignoreList
+}
+
+// skipDir reports whether the files in the directory are never matched
+func (m pathMatcher) skipDir(dir string) bool {
+	if path.Base(dir) == ".git" || m.ignores.ignored(dir, true) {
+		return true
+	}
+
+	for _, pattern := range m.excludes {
+		if matched, _ := doublestar.Match(pattern, dir); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the file is included in the collection
+func (m pathMatcher) match(name string) bool {
+	name = path.Clean(name)
+
+	included := false
+	for _, pattern := range m.includes {
+		if matched, _ := doublestar.Match(pattern, name); matched {
+			included = true
+			break
+		}
+	}
+
+	if !included {
+		return false
+	}
+
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if m.skipDir(dir) {
+			return false
+		}
+	}
+
+	if m.ignores.ignored(name, false) {
+		return false
+	}
+
+	for _, pattern := range m.excludes {
+		if matched, _ := doublestar.Match(pattern, name); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// glob returns the paths of the matching files in the root relative to
+// the root. Only the directories containing the static parts of the
+// include patterns are walked.
+func (m pathMatcher) glob(fsys afero.Fs, root string) ([]string, error) {
+	found := map[string]bool{}
+	for _, pattern := range m.includes {
+		base := filepath.Join(root, globDir(pattern))
+		err := afero.Walk(fsys, base, func(filename string, info fs.FileInfo, err error) error {
+			if err != nil {
+				// collections may point to directories which do not exist yet
+				if filename == base && errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+
+			relFilename, err := filepath.Rel(root, filename)
+			if err != nil {
+				return err
+			}
+			relFilename = filepath.ToSlash(relFilename)
+
+			if info.IsDir() {
+				if relFilename != "." && m.skipDir(relFilename) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if m.match(relFilename) {
+				found[filepath.FromSlash(relFilename)] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filenames := make([]string, 0, len(found))
+	for filename := range found {
+		filenames = append(filenames, filename)
+	}
+	slices.Sort(filenames)
+	return filenames, nil
+}
+
+// loadIgnoreFiles reads the ignore files located in the root. Missing
+// ignore files are skipped.
+func loadIgnoreFiles(fsys afero.Fs, root string, ignoreFiles []string) (ignoreList, error) {
+	rules := ignoreList{}
+	for _, ignoreFile := range ignoreFiles {
+		content, err := afero.ReadFile(fsys, filepath.Join(root, ignoreFile))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		rules = append(rules, parseIgnoreFile(content)...)
+	}
+	return rules, nil
+}
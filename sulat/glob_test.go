@@ -0,0 +1,42 @@
+package sulat
+
+import (
+	"testing"
+)
+
+func TestPathMatcher(t *testing.T) {
+	matcher := pathMatcher{
+		includes: []string{"docs/**/*.md", "guides/*.md"},
+		excludes: []string{"**/_drafts/**"},
+		ignores: parseIgnoreFile([]byte(`
+# build output
+/docs/build/
+*.tmp.md
+secret*.md
+!secret-ok.md
+`)),
+	}
+
+	cases := map[string]bool{
+		"docs/index.md":            true,
+		"docs/a/b/c.md":            true,
+		"guides/setup.md":          true,
+		"guides/deep/setup.md":     false,
+		"docs/_drafts/wip.md":      false,
+		"docs/a/_drafts/b/wip.md":  false,
+		"docs/build/index.md":      false,
+		"docs/a/build/index.md":    true,
+		"docs/notes.tmp.md":        false,
+		"docs/a/secret-key.md":     false,
+		"docs/a/secret-ok.md":      true,
+		"readme.md":                false,
+		"docs/index.json":          false,
+		"docs/.git/description.md": false,
+	}
+
+	for name, expected := range cases {
+		if matched := matcher.match(name); matched != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, matched)
+		}
+	}
+}
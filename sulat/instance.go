@@ -18,6 +18,7 @@ type Instance struct {
 	dataSourceProviders []DataSourceProvider
 	dataSources         []*DataSource
 	codecs              CodecRegistry
+	searchIndex         *SearchIndex
 }
 
 // NewInstance creates a new instance
@@ -36,7 +37,8 @@ func NewInstance(dbLocation string) (*Instance, error) {
 	}
 
 	inst := &Instance{
-		db: db,
+		db:          db,
+		searchIndex: &SearchIndex{db: db},
 	}
 
 	if err := fetchDataSources(&inst.dataSources, inst.db); err != nil {
@@ -54,6 +56,11 @@ func NewInstance(dbLocation string) (*Instance, error) {
 	return inst, nil
 }
 
+// SearchIndex returns the full-text search index of the records
+func (i *Instance) SearchIndex() *SearchIndex {
+	return i.searchIndex
+}
+
 // Sites returns all sites
 func (i *Instance) Sites() ([]*Site, error) {
 	if i.sites == nil {
@@ -91,6 +98,11 @@ func (i *Instance) RemoveSite(siteId string) error {
 		return err
 	}
 
+	if err := i.searchIndex.RemoveCollection(site.Id, ""); err != nil {
+		return err
+	}
+	i.searchIndex.invalidate(site.Id, "")
+
 	i.sites = slices.DeleteFunc(i.sites, func(s *Site) bool {
 		return s.Id == siteId
 	})
@@ -163,21 +175,23 @@ func (i *Instance) DataSourceProviders() []DataSourceProvider {
 	return i.dataSourceProviders
 }
 
-// DataSources returns all data sources
+// DataSources returns all data sources. Data sources fetched from the
+// database are initialized on first use and their initialization errors
+// are reported by their status.
 func (i *Instance) DataSources() ([]*DataSource, error) {
 	if i.dataSources == nil {
 		if err := fetchDataSources(&i.dataSources, i.db); err != nil {
 			return nil, err
 		}
+	}
 
-		for _, dataSource := range i.dataSources {
-			provider, err := i.FindDataSourceProvider(dataSource.ProviderId)
-			if err != nil {
-				// TODO: accumulate errors
-				continue
-			}
-			dataSource.DataSourceProvider = provider
+	for _, dataSource := range i.dataSources {
+		if dataSource.DataSourceProvider != nil || dataSource.initErr != nil {
+			continue
 		}
+
+		i.attachDataSource(dataSource)
+		dataSource.Initialize()
 	}
 	return i.dataSources, nil
 }
@@ -190,8 +204,10 @@ func (i *Instance) attachDataSource(dataSource *DataSource) *DataSource {
 	return dataSource
 }
 
-// NewDataSource creates a new data source
-func (i *Instance) NewDataSource(id, name string, provider DataSourceProvider, config map[string]any) *DataSource {
+// NewDataSource creates a new data source. Data sources which failed to
+// initialize are still registered and returned along with the error so
+// their status can be checked.
+func (i *Instance) NewDataSource(id, name string, provider DataSourceProvider, config map[string]any) (*DataSource, error) {
 	ds := &DataSource{
 		Id:                 id,
 		Name:               name,
@@ -203,14 +219,22 @@ func (i *Instance) NewDataSource(id, name string, provider DataSourceProvider, c
 	ds = i.attachDataSource(ds)
 	schema := provider.Properties().ConfigSchema
 	if err := schema.Validate(config); err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	if err := ds.Initialize(); err != nil {
-		panic(err)
+	initErr := ds.Initialize()
+
+	// make the data source available to the providers referring
+	// to other data sources by their ids
+	if idx := slices.IndexFunc(i.dataSources, func(existing *DataSource) bool {
+		return existing.Id == id
+	}); idx != -1 {
+		i.dataSources[idx] = ds
+	} else {
+		i.dataSources = append(i.dataSources, ds)
 	}
 
-	return ds
+	return ds, initErr
 }
 
 // FindDataSource finds a data source by id
@@ -236,6 +260,10 @@ func (i *Instance) CreateDataSource(ds DataSource) (*DataSource, error) {
 		return nil, err
 	}
 
+	if err := provider.Properties().ConfigSchema.Validate(ds.Config); err != nil {
+		return nil, err
+	}
+
 	dataSource := &DataSource{
 		instance:           i,
 		Id:                 ds.Id,
@@ -249,6 +277,9 @@ func (i *Instance) CreateDataSource(ds DataSource) (*DataSource, error) {
 		return nil, err
 	}
 
+	// initialization errors are reported by the status of the data source
+	dataSource.Initialize()
+
 	i.dataSources = append(i.dataSources, dataSource)
 	return dataSource, nil
 }
@@ -284,6 +315,8 @@ func (i *Instance) RemoveDataSource(id string) error {
 
 	if err := removeDataSource(dataSource.Id, i.db); err != nil {
 		return err
+	} else if err := dataSource.Close(); err != nil {
+		return err
 	}
 
 	i.dataSources = slices.DeleteFunc(i.dataSources, func(ds *DataSource) bool {
@@ -292,20 +325,46 @@ func (i *Instance) RemoveDataSource(id string) error {
 	return nil
 }
 
-// UpdateDataSource updates a data source
+// UpdateDataSource updates a data source and reinitializes it with its
+// new config. Initialization errors are reported by the status of the
+// data source.
+// ValidateDataSource checks whether the provider of the data source exists
+// and accepts the config of the data source
+func (i *Instance) ValidateDataSource(dataSource *DataSource) error {
+	provider := dataSource.DataSourceProvider
+	if provider == nil || provider.Properties().Id != dataSource.ProviderId {
+		registeredProvider, err := i.FindDataSourceProvider(dataSource.ProviderId)
+		if err != nil {
+			return err
+		}
+		provider = registeredProvider
+	}
+	return provider.Properties().ConfigSchema.Validate(dataSource.Config)
+}
+
 func (i *Instance) UpdateDataSource(dataSource *DataSource) error {
-	if err := updateDataSource(dataSource, i.db); err != nil {
+	if err := i.ValidateDataSource(dataSource); err != nil {
+		return err
+	} else if err := updateDataSource(dataSource, i.db); err != nil {
 		return err
 	}
 
 	for idx, ds := range i.dataSources {
 		if ds.Id == dataSource.Id {
+			// close the replaced data source if it is a different instance
+			if ds != dataSource {
+				if err := ds.Close(); err != nil {
+					return err
+				}
+			}
 			i.dataSources[idx] = dataSource
 			break
 		}
 	}
 
-	return nil
+	// the error is also reported by the status of the data source
+	i.attachDataSource(dataSource)
+	return dataSource.Reinitialize()
 }
 
 // Codecs returns all codecs
@@ -0,0 +1,150 @@
+package sulat
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// pathTemplatePattern matches the placeholders of a path template. A
+// placeholder is a field optionally followed by a time layout used for
+// formatting dates (e.g. {{date:2006}}).
+var pathTemplatePattern = regexp.MustCompile(`\{\{\s*([^{}:\s]+)\s*(?::([^{}]*))?\}\}`)
+
+// pathTemplateDateLayouts are the layouts accepted for the date fields
+var pathTemplateDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// PathTemplate renders the path where a record is stored from its fields.
+// Aside from the fields, the template accepts the {{id}} placeholder for
+// the id of the record without its extension and the {{slug}} placeholder
+// for the slug of the record.
+type PathTemplate struct {
+	// Template is the path with placeholders (e.g. posts/{{date:2006}}/{{slug}}.md)
+	Template string
+
+	// SlugField is the field the slug is generated from if the record has
+	// no "slug" field. Defaults to "title".
+	SlugField string
+}
+
+// Slugify converts the text into a lowercase string of letters, digits
+// and dashes suitable for paths and URLs
+func Slugify(text string) string {
+	slug := &strings.Builder{}
+	pendingDash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && slug.Len() != 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			pendingDash = false
+		} else {
+			pendingDash = true
+		}
+	}
+	return slug.String()
+}
+
+// slug returns the slug of the record
+func (t PathTemplate) slug(record *Record) string {
+	if slug, ok := record.Get("slug").(string); ok && len(slug) != 0 {
+		return Slugify(slug)
+	}
+
+	slugField := t.SlugField
+	if len(slugField) == 0 {
+		slugField = "title"
+	}
+
+	if value := record.Get(slugField); value != nil {
+		if slug := Slugify(fmt.Sprint(value)); len(slug) != 0 {
+			return slug
+		}
+	}
+
+	return Slugify(strings.TrimSuffix(record.Id, filepath.Ext(record.Id)))
+}
+
+// formatDate formats the value of a date field with the layout
+func formatDate(field string, value any, layout string) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		for _, dateLayout := range pathTemplateDateLayouts {
+			if date, err := time.Parse(dateLayout, v); err == nil {
+				return date.Format(layout), nil
+			}
+		}
+	}
+	return "", NewResponseError(http.StatusBadRequest, fmt.Sprintf("field %s is not a date", field))
+}
+
+// Render renders the path of the record. The path is relative to the root
+// of the data source and uses the separator of the operating system.
+func (t PathTemplate) Render(record *Record) (string, error) {
+	var renderErr error
+	rendered := pathTemplatePattern.ReplaceAllStringFunc(t.Template, func(placeholder string) string {
+		matches := pathTemplatePattern.FindStringSubmatch(placeholder)
+		field, layout := matches[1], strings.TrimSpace(matches[2])
+
+		var value string
+		switch {
+		case field == "slug" && len(layout) == 0:
+			value = t.slug(record)
+		case field == "id" && len(layout) == 0:
+			value = Slugify(strings.TrimSuffix(record.Id, filepath.Ext(record.Id)))
+		case len(layout) != 0:
+			formatted, err := formatDate(field, record.Get(field), layout)
+			if err != nil {
+				renderErr = err
+				return ""
+			}
+			value = formatted
+		default:
+			if rawValue := record.Get(field); rawValue != nil {
+				value = Slugify(fmt.Sprint(rawValue))
+			}
+		}
+
+		if len(value) == 0 && renderErr == nil {
+			renderErr = NewResponseError(http.StatusBadRequest, fmt.Sprintf("field %s is required for the path of the record", field))
+		}
+		return value
+	})
+
+	if renderErr != nil {
+		return "", renderErr
+	}
+
+	// reject paths escaping the root of the data source
+	cleaned := path.Clean(filepath.ToSlash(rendered))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", NewResponseError(http.StatusBadRequest, "path of the record is outside of the data source")
+	}
+	return filepath.FromSlash(cleaned), nil
+}
+
+// globDir returns the directory of the glob which does not contain patterns
+func globDir(glob string) string {
+	dir := filepath.ToSlash(glob)
+	if idx := strings.IndexAny(dir, `*?[\`); idx != -1 {
+		dir = dir[:idx]
+	}
+
+	if idx := strings.LastIndex(dir, "/"); idx != -1 {
+		return filepath.FromSlash(dir[:idx])
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package sulat
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPathTemplate(t *testing.T) {
+	record := &Record{
+		Id: "draft.md",
+		Data: map[string]any{
+			"title":     "Ünïcode & Spaces  Everywhere",
+			"category":  "Release Notes",
+			"published": time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	cases := []struct {
+		template string
+		expected string
+		isError  bool
+	}{
+		{template: "posts/{{slug}}.md", expected: "posts/ünïcode-spaces-everywhere.md"},
+		{template: "{{category}}/{{published:2006/01}}/{{id}}.md", expected: "release-notes/2024/03/draft.md"},
+		{template: "posts/{{ published:2006-01-02 }}-{{slug}}.md", expected: "posts/2024-03-09-ünïcode-spaces-everywhere.md"},
+		{template: "posts/{{author}}.md", isError: true},
+		{template: "posts/{{title:2006}}.md", isError: true},
+		{template: "../{{slug}}.md", isError: true},
+	}
+
+	for _, c := range cases {
+		rendered, err := PathTemplate{Template: c.template}.Render(record)
+		if c.isError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", c.template, rendered)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", c.template, err)
+		} else if rendered != filepath.FromSlash(c.expected) {
+			t.Errorf("%s: expected %s, got %s", c.template, c.expected, rendered)
+		}
+	}
+
+	if slug := Slugify("  --Hello, World!-- "); slug != "hello-world" {
+		t.Errorf("Expected 'hello-world', got %s", slug)
+	}
+}
@@ -0,0 +1,121 @@
+package query
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// aggregationOptions are the option keys which are computed over
+// all of the matched items instead of the returned window
+var aggregationOptions = []string{"count", "distinct", "group"}
+
+// GroupCount is the number of items sharing the same value of a field
+type GroupCount struct {
+	Value any `json:"value"`
+	Count int `json:"count"`
+}
+
+// WithoutAggregations returns a copy of the query without the options
+// which are not needed for computing the aggregations (eg. pagination
+// and projection)
+func (q *Query) WithoutAggregations() *Query {
+	return q.WithoutOptions(append(append([]string{"select"}, paginationOptions...), aggregationOptions...)...)
+}
+
+// fieldValues returns the value of the field. Each element of an array
+// value is treated as a separate value.
+func fieldValues(item Accessor, field string) []any {
+	value := item.Get(field)
+	if value == nil {
+		return nil
+	} else if values, ok := toSlice(value); ok {
+		return values
+	}
+	return []any{value}
+}
+
+// Aggregate computes the requested aggregations over the matched items.
+// Array values are expanded so grouping by a list field (eg. tags) counts
+// the items for each element.
+func Aggregate[T Accessor](opts Options, items []T) map[string]any {
+	result := map[string]any{}
+
+	if opts.Count {
+		result["count"] = len(items)
+	}
+
+	if len(opts.Distinct) != 0 {
+		distinct := map[string][]any{}
+		for _, field := range opts.Distinct {
+			values := []any{}
+			for _, item := range items {
+				for _, value := range fieldValues(item, field) {
+					if !containsValue(values, value) {
+						values = append(values, value)
+					}
+				}
+			}
+
+			slices.SortFunc(values, Compare)
+			distinct[field] = values
+		}
+		result["distinct"] = distinct
+	}
+
+	if len(opts.Group) != 0 {
+		groups := map[string][]GroupCount{}
+		for _, field := range opts.Group {
+			counts := []GroupCount{}
+			for _, item := range items {
+				for _, value := range fieldValues(item, field) {
+					idx := slices.IndexFunc(counts, func(c GroupCount) bool {
+						return valuesEqual(c.Value, value)
+					})
+
+					if idx == -1 {
+						counts = append(counts, GroupCount{Value: value, Count: 1})
+					} else {
+						counts[idx].Count++
+					}
+				}
+			}
+
+			slices.SortFunc(counts, func(a, b GroupCount) int {
+				if a.Count != b.Count {
+					return b.Count - a.Count
+				}
+				return Compare(a.Value, b.Value)
+			})
+			groups[field] = counts
+		}
+		result["group"] = groups
+	}
+
+	return result
+}
+
+// Project returns the data of the item containing only the selected
+// fields. Dotted fields are projected into nested objects.
+func Project(item Accessor, fields []string) map[string]any {
+	projected := map[string]any{}
+	for _, field := range fields {
+		value := item.Get(field)
+		if value == nil {
+			continue
+		}
+
+		keys := strings.Split(field, ".")
+		target := projected
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := target[key].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				target[key] = next
+			}
+			target = next
+		}
+		target[keys[len(keys)-1]] = value
+	}
+	return projected
+}
@@ -0,0 +1,108 @@
+package query
+
+import "strconv"
+
+// ElemSelf is the field used within an elem query to refer to
+// the array element itself (eg. elem(scores gt(_ 90)))
+const ElemSelf = "_"
+
+func init() {
+	// elem matches nested queries which refers back to the matchers table
+	matchers[OpElem] = elemMatcher
+}
+
+// elemAccessor exposes an array element as an accessor by prefixing
+// the fields with the path of the element
+type elemAccessor struct {
+	data   Accessor
+	prefix string
+}
+
+func (a elemAccessor) Get(key string) any {
+	if key == ElemSelf {
+		return a.data.Get(a.prefix)
+	}
+	return a.data.Get(a.prefix + "." + key)
+}
+
+// containsValue checks if the array contains the value
+func containsValue(items []any, value any) bool {
+	for _, item := range items {
+		if valuesEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMatcher(q *Query, data Accessor) bool {
+	items, ok := toSlice(data.Get(q.Field))
+	return ok && containsValue(items, q.Value)
+}
+
+func containsAnyMatcher(q *Query, data Accessor) bool {
+	items, ok := toSlice(data.Get(q.Field))
+	if !ok {
+		return false
+	}
+
+	values, ok := toSlice(q.Value)
+	if !ok {
+		return false
+	}
+
+	for _, value := range values {
+		if containsValue(items, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAllMatcher(q *Query, data Accessor) bool {
+	items, ok := toSlice(data.Get(q.Field))
+	if !ok {
+		return false
+	}
+
+	values, ok := toSlice(q.Value)
+	if !ok {
+		return false
+	}
+
+	for _, value := range values {
+		if !containsValue(items, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func sizeMatcher(q *Query, data Accessor) bool {
+	items, ok := toSlice(data.Get(q.Field))
+	if !ok {
+		return false
+	}
+
+	size, err := decodeIntOption("size", q.Value)
+	return err == nil && len(items) == size
+}
+
+func elemMatcher(q *Query, data Accessor) bool {
+	subQuery, ok := q.Value.(*Query)
+	if !ok {
+		return false
+	}
+
+	items, ok := toSlice(data.Get(q.Field))
+	if !ok {
+		return false
+	}
+
+	for i := range items {
+		if subQuery.Match(elemAccessor{data: data, prefix: q.Field + "." + strconv.Itoa(i)}) {
+			return true
+		}
+	}
+	return false
+}
@@ -48,6 +48,26 @@ func Nlike(field string, value any) *Query {
 	return buildQuery(OpNlike, field, value)
 }
 
+func Ilike(field string, value any) *Query {
+	return buildQuery(OpIlike, field, value)
+}
+
+func Nilike(field string, value any) *Query {
+	return buildQuery(OpNilike, field, value)
+}
+
+func StartsWith(field string, value any) *Query {
+	return buildQuery(OpStartsWith, field, value)
+}
+
+func EndsWith(field string, value any) *Query {
+	return buildQuery(OpEndsWith, field, value)
+}
+
+func Regex(field string, value any) *Query {
+	return buildQuery(OpRegex, field, value)
+}
+
 func Isnull(field string, value any) *Query {
 	return buildQuery(OpIsnull, field, value)
 }
@@ -64,6 +84,26 @@ func Nbetween(field string, value []any) *Query {
 	return buildQuery(OpNbetween, field, value)
 }
 
+func Contains(field string, value any) *Query {
+	return buildQuery(OpContains, field, value)
+}
+
+func ContainsAny(field string, value []any) *Query {
+	return buildQuery(OpContainsAny, field, value)
+}
+
+func ContainsAll(field string, value []any) *Query {
+	return buildQuery(OpContainsAll, field, value)
+}
+
+func Size(field string, value int) *Query {
+	return buildQuery(OpSize, field, value)
+}
+
+func Elem(field string, query *Query) *Query {
+	return buildQuery(OpElem, field, query)
+}
+
 func And(queries ...*Query) *Query {
 	return buildQuery(OpAnd, "", queries)
 }
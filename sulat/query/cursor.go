@@ -0,0 +1,106 @@
+package query
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Cursor marks the position of an item within the sorted items. Cursors
+// are passed around as opaque tokens in the cursor option.
+type Cursor struct {
+	// Values are the sort key values of the item
+	Values []any `json:"v"`
+
+	// Id is the id of the item
+	Id any `json:"id"`
+
+	// Before selects the items before the position instead of after it
+	Before bool `json:"b,omitempty"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// NewCursor creates a cursor pointing to the item
+func NewCursor(item Accessor, order []OrderBy, before bool) *Cursor {
+	values := make([]any, len(order))
+	for i, key := range order {
+		values[i] = item.Get(key.Field)
+	}
+
+	return &Cursor{
+		Values: values,
+		Id:     item.Get("id"),
+		Before: before,
+	}
+}
+
+// Encode encodes the cursor into an opaque token
+func (c *Cursor) Encode() string {
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor decodes a cursor from a token created by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(cursor); err != nil {
+		return nil, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// compareToCursor compares the item against the position of the cursor
+func compareToCursor(item Accessor, cursor *Cursor, order []OrderBy) int {
+	for i, key := range order {
+		if i >= len(cursor.Values) {
+			break
+		}
+
+		result := Compare(item.Get(key.Field), cursor.Values[i])
+		if key.Descending {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return Compare(item.Get("id"), cursor.Id)
+}
+
+// seekCursor returns the sorted items after (or before) the cursor
+func seekCursor[T Accessor](items []T, opts Options) []T {
+	if opts.Cursor == nil {
+		return items
+	}
+
+	if opts.Cursor.Before {
+		end := 0
+		for end < len(items) && compareToCursor(items[end], opts.Cursor, opts.Order) < 0 {
+			end++
+		}
+
+		items = items[:end]
+		if opts.Limit > 0 && opts.Limit < len(items) {
+			items = items[len(items)-opts.Limit:]
+		}
+		return items
+	}
+
+	start := 0
+	for start < len(items) && compareToCursor(items[start], opts.Cursor, opts.Order) <= 0 {
+		start++
+	}
+	return items[start:]
+}
@@ -0,0 +1,63 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestCursorPagination(t *testing.T) {
+	records := []testRecord{
+		{"id": "a", "rank": 1},
+		{"id": "b", "rank": 3},
+		{"id": "c", "rank": 2},
+		{"id": "d", "rank": 2},
+		{"id": "e", "rank": 5},
+	}
+
+	base, err := ParseFromString(`{order:["rank","desc"], limit:2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, _ := base.ParseOptions()
+	fetch := func(t *testing.T, items []testRecord, cursor *Cursor) []testRecord {
+		q := base
+		if cursor != nil {
+			q = base.WithOption("cursor", cursor.Encode())
+		}
+
+		result, err := Apply(q, append([]testRecord{}, items...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	firstPage := fetch(t, records, nil)
+	if diff := deep.Equal(recordIds(firstPage), []any{"e", "b"}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	secondPage := fetch(t, records, NewCursor(firstPage[1], opts.Order, false))
+	if diff := deep.Equal(recordIds(secondPage), []any{"c", "d"}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// removing an item from a previous page should not shift the next page
+	lastPage := fetch(t, records[:4], NewCursor(secondPage[1], opts.Order, false))
+	if diff := deep.Equal(recordIds(lastPage), []any{"a"}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	prevPage := fetch(t, records, NewCursor(secondPage[0], opts.Order, true))
+	if diff := deep.Equal(recordIds(prevPage), []any{"e", "b"}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		if _, err := Apply(base.WithOption("cursor", "not a cursor"), records); err == nil {
+			t.Fatal("Expected error for invalid cursor")
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonQuery is the JSON representation of a query. Logical queries list
+// their nested queries in children while elem queries store the nested
+// query as the value. Placeholders are written as {"$param":"name"}.
+type jsonQuery struct {
+	Operator Operator          `json:"operator,omitempty"`
+	Field    string            `json:"field,omitempty"`
+	Value    json.RawMessage   `json:"value,omitempty"`
+	Children []json.RawMessage `json:"children,omitempty"`
+	Options  json.RawMessage   `json:"options,omitempty"`
+}
+
+func (q *Query) MarshalJSON() ([]byte, error) {
+	encoded := struct {
+		Operator Operator       `json:"operator,omitempty"`
+		Field    string         `json:"field,omitempty"`
+		Value    any            `json:"value,omitempty"`
+		Children []*Query       `json:"children,omitempty"`
+		Options  map[string]any `json:"options,omitempty"`
+	}{
+		Operator: q.Operator,
+		Field:    q.Field,
+		Options:  q.Options,
+	}
+
+	if q.Operator.IsLogical() {
+		encoded.Children, _ = q.Value.([]*Query)
+	} else {
+		encoded.Value = q.Value
+	}
+
+	return json.Marshal(encoded)
+}
+
+func (q *Query) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	decoded, err := decodeJSONQuery(data, "")
+	if err != nil {
+		return err
+	}
+
+	*q = *decoded
+	return nil
+}
+
+// ParseFromJSON parses a query from its JSON representation. An empty
+// input returns a nil query which matches all records.
+func ParseFromJSON(data []byte) (*Query, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	return decodeJSONQuery(data, "")
+}
+
+func decodeJSONQuery(data []byte, path string) (*Query, error) {
+	invalid := func(f string, a ...any) error {
+		return &ValidationError{
+			Path:    path,
+			Message: fmt.Sprintf(f, a...),
+		}
+	}
+
+	raw := jsonQuery{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, invalid("invalid query: %s", err.Error())
+	}
+
+	query := &Query{
+		Operator: raw.Operator,
+		Field:    raw.Field,
+	}
+
+	if len(raw.Options) != 0 {
+		if err := decodeJSONValue(raw.Options, &query.Options); err != nil {
+			return nil, invalid("invalid options: %s", err.Error())
+		}
+		decodePlaceholders(query.Options)
+	}
+
+	if len(query.Operator) == 0 {
+		if len(query.Field) != 0 || len(raw.Value) != 0 || raw.Children != nil {
+			return nil, invalid("operator is required")
+		}
+		return query, nil
+	} else if !query.Operator.IsSupported() {
+		return nil, invalid("unsupported operator %q", query.Operator)
+	}
+
+	if query.Operator.IsLogical() {
+		if len(raw.Value) != 0 || len(query.Field) != 0 {
+			return nil, invalid("%s only accepts children", query.Operator)
+		}
+
+		var children []*Query
+		for i, rawChild := range raw.Children {
+			child, err := decodeJSONQuery(rawChild, childQueryPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+
+		if len(children) > 0 {
+			query.Value = children
+		}
+		return query, nil
+	}
+
+	if raw.Children != nil {
+		return nil, invalid("children are only allowed in logical queries")
+	} else if len(query.Field) == 0 {
+		return nil, invalid("field is required")
+	} else if len(raw.Value) == 0 {
+		return query, nil
+	}
+
+	if query.Operator == OpElem {
+		subQuery, err := decodeJSONQuery(raw.Value, childQueryPath(path, 0))
+		if err != nil {
+			return nil, err
+		}
+		query.Value = subQuery
+		return query, nil
+	}
+
+	if err := decodeJSONValue(raw.Value, &query.Value); err != nil {
+		return nil, invalid("invalid value: %s", err.Error())
+	}
+
+	query.Value = decodePlaceholders(query.Value)
+	return query, nil
+}
+
+// decodeJSONValue decodes the value while keeping numbers as json.Number
+// which is the same representation produced by the query parser
+func decodeJSONValue(data []byte, dest any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(dest)
+}
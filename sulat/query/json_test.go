@@ -0,0 +1,107 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestQueryJSON(t *testing.T) {
+	tests := []struct {
+		dsl  string
+		json string
+	}{
+		{
+			dsl:  `eq(id 1)`,
+			json: `{"operator":"eq","field":"id","value":1}`,
+		},
+		{
+			dsl:  `isnull(deleted_at)`,
+			json: `{"operator":"isnull","field":"deleted_at"}`,
+		},
+		{
+			dsl:  `in(tags ["go",["nested",2]])`,
+			json: `{"operator":"in","field":"tags","value":["go",["nested",2]]}`,
+		},
+		{
+			dsl:  `and(eq(id 1),gt(views -2.5),{limit:10,order:["id","desc"]})`,
+			json: `{"operator":"and","children":[{"operator":"eq","field":"id","value":1},{"operator":"gt","field":"views","value":-2.5}],"options":{"limit":10,"order":["id","desc"]}}`,
+		},
+		{
+			dsl:  `elem(authors and(eq(role "editor"),eq(active true)))`,
+			json: `{"operator":"elem","field":"authors","value":{"operator":"and","children":[{"operator":"eq","field":"role","value":"editor"},{"operator":"eq","field":"active","value":true}]}}`,
+		},
+		{
+			dsl:  `{limit:5,custom:{deep:{hello:"world"}}}`,
+			json: `{"options":{"custom":{"deep":{"hello":"world"}},"limit":5}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsl, func(t *testing.T) {
+			fromDSL, err := ParseFromString(tt.dsl)
+			if err != nil {
+				t.Fatalf("Error parsing query: %v", err)
+			}
+
+			fromJSON, err := ParseFromJSON([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("Error decoding query: %v", err)
+			}
+
+			if diff := deep.Equal(fromJSON, fromDSL); diff != nil {
+				t.Error(diff)
+			}
+
+			encoded, err := json.Marshal(fromDSL)
+			if err != nil {
+				t.Fatalf("Error encoding query: %v", err)
+			} else if string(encoded) != tt.json {
+				t.Errorf("Expected %s, got %s", tt.json, encoded)
+			}
+
+			decoded := &Query{}
+			if err := json.Unmarshal(encoded, decoded); err != nil {
+				t.Fatalf("Error decoding query: %v", err)
+			} else if diff := deep.Equal(decoded, fromDSL); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("Empty input", func(t *testing.T) {
+		result, err := ParseFromJSON([]byte(" "))
+		if err != nil {
+			t.Fatal(err)
+		} else if result != nil {
+			t.Fatalf("Expected nil query, got %s", result)
+		}
+	})
+
+	invalidTests := []struct {
+		json string
+		path string
+	}{
+		{json: `{"operator":"matches","field":"id","value":1}`, path: ""},
+		{json: `{"operator":"eq","value":1}`, path: ""},
+		{json: `{"operator":"eq","field":"id","children":[]}`, path: ""},
+		{json: `{"operator":"and","children":[{"operator":"eq","field":"id"},{"operator":"eq","value":1}]}`, path: "1"},
+		{json: `{"operator":"or","children":[{"operator":"and","children":[{"operator":"foo"}]}]}`, path: "0.0"},
+		{json: `{"operator":"and","value":[]}`, path: ""},
+		{json: `{"operator":"eq","field":"id","valeu":1}`, path: ""},
+		{json: `{"field":"id"}`, path: ""},
+	}
+
+	for _, tt := range invalidTests {
+		t.Run(tt.json, func(t *testing.T) {
+			_, err := ParseFromJSON([]byte(tt.json))
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Expected a validation error, got %v", err)
+			} else if validationErr.Path != tt.path {
+				t.Errorf("Expected error at %q, got %q (%s)", tt.path, validationErr.Path, validationErr.Message)
+			}
+		})
+	}
+}
@@ -2,7 +2,12 @@ package query
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -14,11 +19,22 @@ type Accessor interface {
 type MatcherFunc func(q *Query, data Accessor) bool
 
 func eqMatcher(q *Query, data Accessor) bool {
-	return cmp.Equal(data.Get(q.Field), q.Value)
+	return valuesEqual(data.Get(q.Field), q.Value)
 }
 
-func likeMatcher(q *Query, data Accessor) bool {
-	return strings.Contains(data.Get(q.Field).(string), q.Value.(string))
+func inMatcher(q *Query, data Accessor) bool {
+	values, ok := toSlice(q.Value)
+	if !ok {
+		return false
+	}
+
+	value := data.Get(q.Field)
+	for _, v := range values {
+		if valuesEqual(value, v) {
+			return true
+		}
+	}
+	return false
 }
 
 func isnullMatcher(q *Query, data Accessor) bool {
@@ -58,6 +74,17 @@ func decodeNumberValue(value any) (fl64 numberC[float64], i64 numberC[int64]) {
 	case int:
 		i64.val = int64(v)
 		i64.set = true
+	case int8, int16, int32, uint, uint8, uint16, uint32:
+		i64.val = reflect.ValueOf(v).Convert(reflect.TypeOf(int64(0))).Int()
+		i64.set = true
+	case uint64:
+		if v > math.MaxInt64 {
+			fl64.val = float64(v)
+			fl64.set = true
+		} else {
+			i64.val = int64(v)
+			i64.set = true
+		}
 	}
 	return
 }
@@ -79,9 +106,9 @@ func compareNumbersFn(f1 numberC[float64], i1 numberC[int64], f2 numberC[float64
 	return false
 }
 
-func compareNumbers(op Operator) MatcherFunc {
+func compareMatcher(op Operator) MatcherFunc {
 	if !op.IsComparative() {
-		panic("compareValues called with non-comparative operator")
+		panic("compareMatcher called with non-comparative operator")
 	}
 
 	return func(q *Query, data Accessor) bool {
@@ -90,50 +117,230 @@ func compareNumbers(op Operator) MatcherFunc {
 			return false
 		}
 
-		fl64FromQ, i64FromQ := decodeNumberValue(q.Value)
-		fl64FromV, i64FromV := decodeNumberValue(value)
-
-		return compareNumbersFn(fl64FromQ, i64FromQ, fl64FromV, i64FromV, func(a, b float64) bool {
-			switch op {
-			case OpGt:
-				return a > b
-			case OpGte:
-				return a >= b
-			case OpLt:
-				return a < b
-			case OpLte:
-				return a <= b
-			}
+		result, ok := compareValues(value, q.Value)
+		if !ok {
 			return false
-		})
+		}
+
+		switch op {
+		case OpGt:
+			return result > 0
+		case OpGte:
+			return result >= 0
+		case OpLt:
+			return result < 0
+		case OpLte:
+			return result <= 0
+		}
+		return false
 	}
 }
 
-var matchers = map[Operator]MatcherFunc{
-	OpEq:  eqMatcher,
-	OpNeq: inverseMatch(eqMatcher),
-	OpGt:  compareNumbers(OpGt),
-	OpGte: compareNumbers(OpGte),
-	OpLt:  compareNumbers(OpLt),
-	OpLte: compareNumbers(OpLte),
-	OpIn: func(q *Query, data Accessor) bool {
-		for _, v := range q.Value.([]any) {
-			if v == data.Get(q.Field) {
-				return true
+// betweenMatcher matches values within the inclusive [lower, upper] range
+func betweenMatcher(q *Query, data Accessor) bool {
+	value := data.Get(q.Field)
+	if value == nil {
+		return false
+	}
+
+	bounds, ok := toSlice(q.Value)
+	if !ok || len(bounds) != 2 {
+		return false
+	}
+
+	lower, ok := compareValues(value, bounds[0])
+	if !ok || lower < 0 {
+		return false
+	}
+
+	upper, ok := compareValues(value, bounds[1])
+	return ok && upper <= 0
+}
+
+// timeLayouts are the accepted layouts of date and time values
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func decodeTimeValue(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
 			}
 		}
-		return false
-	},
-	OpNin: func(q *Query, data Accessor) bool {
-		for _, v := range q.Value.([]any) {
-			if v == data.Get(q.Field) {
-				return false
+	}
+	return time.Time{}, false
+}
+
+// valuesEqual checks if both values are equal. Numbers are compared by
+// their value regardless of their type.
+func valuesEqual(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	} else if result, ok := compareValues(a, b); ok {
+		return result == 0
+	}
+	return cmp.Equal(a, b)
+}
+
+// toText converts a scalar value into its text form
+func toText(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// toSlice converts a slice or an array of any type into []any
+func toSlice(value any) ([]any, bool) {
+	if v, ok := value.([]any); ok {
+		return v, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// compareValues compares two scalar values of the same kind. The second
+// return value is false if the values cannot be compared with each other.
+func compareValues(a, b any) (int, bool) {
+	fl64A, i64A := decodeNumberValue(a)
+	fl64B, i64B := decodeNumberValue(b)
+	if (fl64A.set || i64A.set) && (fl64B.set || i64B.set) {
+		result := 0
+		compareNumbersFn(fl64A, i64A, fl64B, i64B, func(x, y float64) bool {
+			result = compareOrdered(x, y)
+			return true
+		})
+		return result, true
+	}
+
+	if ta, ok := decodeTimeValue(a); ok {
+		if tb, ok := decodeTimeValue(b); ok {
+			return ta.Compare(tb), true
+		}
+	}
+
+	switch va := a.(type) {
+	case string:
+		if vb, ok := b.(string); ok {
+			return strings.Compare(va, vb), true
+		}
+	case bool:
+		if vb, ok := b.(bool); ok {
+			if va == vb {
+				return 0, true
+			} else if !va {
+				return -1, true
 			}
+			return 1, true
 		}
-		return true
-	},
-	OpLike:    likeMatcher,
-	OpNlike:   inverseMatch(likeMatcher),
-	OpIsnull:  isnullMatcher,
-	OpNotnull: inverseMatch(isnullMatcher),
+	}
+
+	return 0, false
+}
+
+func compareOrdered[T int | float64](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// valueRank orders values of different kinds when sorting
+func valueRank(v any) int {
+	if v == nil {
+		return 0
+	}
+
+	switch v.(type) {
+	case bool:
+		return 1
+	case string:
+		return 3
+	}
+
+	if fl64, i64 := decodeNumberValue(v); fl64.set || i64.set {
+		return 2
+	}
+	return 4
+}
+
+// Compare compares two values for sorting. Values which are not
+// comparable with each other are ordered by their kind with nil
+// values placed first. Arrays and objects are ordered by their JSON
+// encoding the same way as in SQL.
+func Compare(a, b any) int {
+	if result, ok := compareValues(a, b); ok {
+		return result
+	}
+
+	rankA, rankB := valueRank(a), valueRank(b)
+	if rankA != rankB {
+		return compareOrdered(rankA, rankB)
+	}
+	return strings.Compare(encodeCompareValue(a), encodeCompareValue(b))
+}
+
+func encodeCompareValue(v any) string {
+	if encoded, err := json.Marshal(v); err == nil {
+		return string(encoded)
+	}
+	return fmt.Sprint(v)
+}
+
+var matchers = map[Operator]MatcherFunc{
+	OpEq:          eqMatcher,
+	OpNeq:         inverseMatch(eqMatcher),
+	OpGt:          compareMatcher(OpGt),
+	OpGte:         compareMatcher(OpGte),
+	OpLt:          compareMatcher(OpLt),
+	OpLte:         compareMatcher(OpLte),
+	OpIn:          inMatcher,
+	OpNin:         inverseMatch(inMatcher),
+	OpLike:        likeMatcher,
+	OpNlike:       inverseMatch(likeMatcher),
+	OpIlike:       ilikeMatcher,
+	OpNilike:      inverseMatch(ilikeMatcher),
+	OpStartsWith:  textMatcher(strings.HasPrefix),
+	OpEndsWith:    textMatcher(strings.HasSuffix),
+	OpRegex:       regexMatcher,
+	OpContains:    containsMatcher,
+	OpContainsAny: containsAnyMatcher,
+	OpContainsAll: containsAllMatcher,
+	OpSize:        sizeMatcher,
+	OpIsnull:      isnullMatcher,
+	OpNotnull:     inverseMatch(isnullMatcher),
+	OpBetween:     betweenMatcher,
+	OpNbetween:    inverseMatch(betweenMatcher),
 }
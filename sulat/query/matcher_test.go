@@ -0,0 +1,202 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+type matcherTest struct {
+	query    string
+	expected bool
+}
+
+func runMatcherTests(t *testing.T, data Accessor, tests []matcherTest) {
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseFromString(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := q.Match(data); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestComparisonMatchers(t *testing.T) {
+	data := testRecord{
+		"id":           "post",
+		"views":        120,
+		"rating":       4.5,
+		"title":        "Hello",
+		"published_at": "2024-03-15T10:00:00Z",
+		"updated_at":   time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	runMatcherTests(t, data, []matcherTest{
+		{`gt(views 100)`, true},
+		{`gt(views 120)`, false},
+		{`gte(views 120)`, true},
+		{`lt(views 120.5)`, true},
+		{`lte(rating 4)`, false},
+		{`gt(title "A")`, true},
+		{`gt(missing 1)`, false},
+		{`gt(title 1)`, false},
+		{`between(views [100, 120])`, true},
+		{`between(views [121, 200])`, false},
+		{`nbetween(views [121, 200])`, true},
+		{`between(rating [4, 5])`, true},
+		{`between(published_at ["2024-01-01","2024-06-30"])`, true},
+		{`between(published_at ["2024-03-16","2024-06-30"])`, false},
+		{`nbetween(published_at ["2024-03-16","2024-06-30"])`, true},
+		{`gt(published_at "2024-03-15T09:00:00Z")`, true},
+		{`gt(published_at "2024-03-15T12:00:00+03:00")`, true},
+		{`lt(published_at "2024-03-15")`, false},
+		{`gt(updated_at "2024-05-01")`, true},
+		{`lte(updated_at "2024-05-09T23:59:59Z")`, false},
+		{`between(updated_at ["2024-05-10","2024-05-10"])`, true},
+	})
+}
+
+func TestMatchersTolerateTypes(t *testing.T) {
+	data := testRecord{
+		"id":    "post",
+		"views": 120,
+		"title": "Hello World",
+		"tags":  []any{"go", "cms"},
+	}
+
+	runMatcherTests(t, data, []matcherTest{
+		{`eq(views 120)`, true},
+		{`eq(views 120.0)`, true},
+		{`eq(views "120")`, false},
+		{`neq(missing 1)`, true},
+		{`like(views 12)`, true},
+		{`like(missing "a")`, false},
+		{`like(tags "go")`, false},
+		{`nlike(title "World")`, false},
+		{`in(views [1, 120])`, true},
+		{`in(views 120)`, false},
+		{`nin(title ["Hello"])`, true},
+		{`between(views 120)`, false},
+		{`gt(views [1])`, false},
+	})
+
+	t.Run("Unsupported operator", func(t *testing.T) {
+		if (&Query{Operator: "unknown", Field: "id"}).Match(data) {
+			t.Error("Expected unsupported operator not to match")
+		}
+	})
+
+	t.Run("Logical query without entries", func(t *testing.T) {
+		if !And().Match(data) {
+			t.Error("Expected empty and() to match")
+		}
+		if Or().Match(data) {
+			t.Error("Expected empty or() not to match")
+		}
+	})
+}
+
+func TestTextMatchers(t *testing.T) {
+	data := testRecord{
+		"id":    "post",
+		"title": "Hello World",
+		"slug":  "100%_done",
+		"views": 120,
+	}
+
+	runMatcherTests(t, data, []matcherTest{
+		{`like(title "World")`, true},
+		{`like(title "world")`, false},
+		{`like(title "Hello%")`, true},
+		{`like(title "%World")`, true},
+		{`like(title "Hello")`, true},
+		{`like(title "H_llo World")`, true},
+		{`like(title "H_llo")`, false},
+		{`like(title "hello%")`, false},
+		{`like(slug "100\\%\\_done")`, true},
+		{`like(slug "100\\%x%")`, false},
+		{`ilike(title "hello%")`, true},
+		{`ilike(title "WORLD")`, true},
+		{`nilike(title "WORLD")`, false},
+		{`startswith(title "Hello")`, true},
+		{`startswith(title "World")`, false},
+		{`endswith(title "World")`, true},
+		{`endswith(views 20)`, true},
+		{`regex(title "^Hel+o\\s")`, true},
+		{`regex(title "^world")`, false},
+		{`regex(title "(?i)WORLD$")`, true},
+		{`regex(title "[")`, false},
+	})
+
+	t.Run("Builders", func(t *testing.T) {
+		if !And(Ilike("title", "hello%"), StartsWith("title", "He"), EndsWith("title", "ld"), Regex("title", "o W")).Match(data) {
+			t.Error("Expected builder queries to match")
+		}
+	})
+
+	t.Run("Invalid regular expression", func(t *testing.T) {
+		if err := Regex("title", "[").Validate(); err == nil {
+			t.Error("Expected invalid regular expression to be reported")
+		}
+	})
+}
+
+func TestArrayMatchers(t *testing.T) {
+	data := testRecord{
+		"id":     "post",
+		"tags":   []any{"go", "cms"},
+		"scores": []any{80, 95},
+		"title":  "Hello",
+		"authors": []any{
+			map[string]any{"name": "Jane", "role": "editor", "posts": 3},
+			map[string]any{"name": "John", "role": "writer", "posts": 10},
+		},
+	}
+
+	runMatcherTests(t, data, []matcherTest{
+		{`contains(tags "go")`, true},
+		{`contains(tags "rust")`, false},
+		{`contains(scores 95.0)`, true},
+		{`contains(title "H")`, false},
+		{`containsany(tags ["rust","cms"])`, true},
+		{`containsany(tags ["rust"])`, false},
+		{`containsall(tags ["cms","go"])`, true},
+		{`containsall(tags ["cms","rust"])`, false},
+		{`size(tags 2)`, true},
+		{`size(tags 3)`, false},
+		{`size(title 5)`, false},
+		{`elem(authors eq(role "editor"))`, true},
+		{`elem(authors and(eq(role "editor"),gt(posts 5)))`, false},
+		{`elem(authors and(eq(role "writer"),gt(posts 5)))`, true},
+		{`elem(scores gt(_ 90))`, true},
+		{`elem(scores gt(_ 95))`, false},
+		{`elem(title eq(_ "Hello"))`, false},
+	})
+
+	t.Run("Builders", func(t *testing.T) {
+		q := And(
+			Contains("tags", "go"),
+			ContainsAll("tags", []any{"go", "cms"}),
+			Size("authors", 2),
+			Elem("authors", Eq("name", "John")),
+		)
+
+		if err := q.Validate(); err != nil {
+			t.Fatal(err)
+		} else if !q.Match(data) {
+			t.Error("Expected builder queries to match")
+		}
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		for _, q := range []*Query{Size("tags", -1), {Operator: OpContainsAny, Field: "tags", Value: "go"}, Elem("authors", nil), Elem("authors", In("role", "editor"))} {
+			if err := q.Validate(); err == nil {
+				t.Errorf("Expected %s to be invalid", q)
+			}
+		}
+	})
+}
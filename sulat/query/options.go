@@ -0,0 +1,289 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// paginationOptions are the option keys which only affect the window
+// of the matched items
+var paginationOptions = []string{"limit", "offset", "cursor"}
+
+// OrderBy is a single sort key of a query
+type OrderBy struct {
+	Field      string
+	Descending bool
+}
+
+// Options is the decoded form of the options block of a query
+type Options struct {
+	Limit  int
+	Offset int
+	Order  []OrderBy
+	Cursor *Cursor
+
+	// Select are the fields to be included in the matched items
+	Select []string
+
+	// Count, Distinct and Group are the aggregations to be computed
+	// over the matched items
+	Count    bool
+	Distinct []string
+	Group    []string
+}
+
+// HasAggregations checks if any aggregation is requested
+func (opts Options) HasAggregations() bool {
+	return opts.Count || len(opts.Distinct) != 0 || len(opts.Group) != 0
+}
+
+// ParseOptions decodes the options block of the query
+func (q *Query) ParseOptions() (Options, error) {
+	opts := Options{}
+	if q == nil || q.Options == nil {
+		return opts, nil
+	}
+
+	if rawLimit, ok := q.Options["limit"]; ok {
+		limit, err := decodeIntOption("limit", rawLimit)
+		if err != nil {
+			return opts, err
+		}
+		opts.Limit = limit
+	}
+
+	if rawOffset, ok := q.Options["offset"]; ok {
+		offset, err := decodeIntOption("offset", rawOffset)
+		if err != nil {
+			return opts, err
+		}
+		opts.Offset = offset
+	}
+
+	if rawOrder, ok := q.Options["order"]; ok {
+		order, err := decodeOrderOption(rawOrder)
+		if err != nil {
+			return opts, err
+		}
+		opts.Order = order
+	}
+
+	if rawCursor, ok := q.Options["cursor"]; ok {
+		token, ok := rawCursor.(string)
+		if !ok {
+			return opts, fmt.Errorf("cursor must be a string")
+		}
+
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cursor = cursor
+	}
+
+	if rawSelect, ok := q.Options["select"]; ok {
+		fields, err := decodeFieldsOption("select", rawSelect)
+		if err != nil {
+			return opts, err
+		}
+		opts.Select = fields
+	}
+
+	if rawCount, ok := q.Options["count"]; ok {
+		count, ok := rawCount.(bool)
+		if !ok {
+			return opts, fmt.Errorf("count must be a boolean")
+		}
+		opts.Count = count
+	}
+
+	if rawDistinct, ok := q.Options["distinct"]; ok {
+		fields, err := decodeFieldsOption("distinct", rawDistinct)
+		if err != nil {
+			return opts, err
+		}
+		opts.Distinct = fields
+	}
+
+	if rawGroup, ok := q.Options["group"]; ok {
+		fields, err := decodeFieldsOption("group", rawGroup)
+		if err != nil {
+			return opts, err
+		}
+		opts.Group = fields
+	}
+
+	return opts, nil
+}
+
+// WithoutPagination returns a copy of the query without the options
+// which limit the window of the matched items
+func (q *Query) WithoutPagination() *Query {
+	return q.WithoutOptions(paginationOptions...)
+}
+
+// WithoutOptions returns a copy of the query without the given options
+func (q *Query) WithoutOptions(keys ...string) *Query {
+	if q == nil || q.Options == nil {
+		return q
+	}
+
+	newQuery := *q
+	newQuery.Options = maps.Clone(q.Options)
+	for _, key := range keys {
+		delete(newQuery.Options, key)
+	}
+
+	if len(newQuery.Options) == 0 {
+		newQuery.Options = nil
+	}
+	return &newQuery
+}
+
+// WithOption returns a copy of the query with the option set. A nil
+// query results into an options-only query.
+func (q *Query) WithOption(key string, value any) *Query {
+	newQuery := &Query{}
+	if q != nil {
+		*newQuery = *q
+	}
+
+	newQuery.Options = maps.Clone(newQuery.Options)
+	if newQuery.Options == nil {
+		newQuery.Options = map[string]any{}
+	}
+	newQuery.Options[key] = value
+	return newQuery
+}
+
+func decodeIntOption(key string, value any) (int, error) {
+	fl64, i64 := decodeNumberValue(value)
+	if i64.set && i64.val >= 0 {
+		return int(i64.val), nil
+	} else if fl64.set && fl64.val >= 0 && fl64.val == float64(int64(fl64.val)) {
+		return int(fl64.val), nil
+	}
+	return 0, fmt.Errorf("%s must be a non-negative integer", key)
+}
+
+// decodeFieldsOption decodes an option which accepts a field
+// or a list of fields
+func decodeFieldsOption(key string, value any) ([]string, error) {
+	if field, ok := value.(string); ok {
+		return []string{field}, nil
+	}
+
+	items, ok := toSlice(value)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a field or a list of fields", key)
+	}
+
+	fields := make([]string, len(items))
+	for i, item := range items {
+		field, ok := item.(string)
+		if !ok || len(field) == 0 {
+			return nil, fmt.Errorf("%s must be a field or a list of fields", key)
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// decodeOrderOption decodes the order option. The option accepts a list of
+// fields with each field optionally followed by "asc" or "desc" (eg.
+// ["date","desc","title"]) or a list of [field, direction] pairs.
+func decodeOrderOption(value any) ([]OrderBy, error) {
+	var order []OrderBy
+
+	switch v := value.(type) {
+	case string:
+		return []OrderBy{{Field: v}}, nil
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return decodeOrderOption(items)
+	case []any:
+		for _, item := range v {
+			switch vv := item.(type) {
+			case string:
+				if dir := strings.ToLower(vv); dir == "asc" || dir == "desc" {
+					if len(order) == 0 {
+						return nil, fmt.Errorf("order direction %q must follow a field", vv)
+					}
+					order[len(order)-1].Descending = dir == "desc"
+					continue
+				}
+				order = append(order, OrderBy{Field: vv})
+			case []any:
+				pair, err := decodeOrderOption(vv)
+				if err != nil {
+					return nil, err
+				} else if len(pair) != 1 {
+					return nil, fmt.Errorf("order pair must only contain a field and a direction")
+				}
+				order = append(order, pair...)
+			default:
+				return nil, fmt.Errorf("invalid order entry: %v", item)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("order must be a field or a list of fields")
+	}
+
+	return order, nil
+}
+
+// Sort sorts the items based on the given sort keys. Items with equal
+// sort keys are ordered by their id so the result is always deterministic.
+func Sort[T Accessor](items []T, order []OrderBy) {
+	slices.SortStableFunc(items, func(a, b T) int {
+		return compareItems(a, b, order)
+	})
+}
+
+func compareItems(a, b Accessor, order []OrderBy) int {
+	for _, key := range order {
+		result := Compare(a.Get(key.Field), b.Get(key.Field))
+		if key.Descending {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return Compare(a.Get("id"), b.Get("id"))
+}
+
+// Paginate returns the window of the sorted items specified by the
+// cursor, limit and offset options. The offset is ignored if a cursor
+// is given.
+func Paginate[T Accessor](items []T, opts Options) []T {
+	if opts.Cursor != nil {
+		items = seekCursor(items, opts)
+	} else if opts.Offset >= len(items) {
+		return []T{}
+	} else {
+		items = items[opts.Offset:]
+	}
+
+	if opts.Limit > 0 && opts.Limit < len(items) {
+		items = items[:opts.Limit]
+	}
+	return items
+}
+
+// Apply sorts and paginates the matched items based on the query options
+func Apply[T Accessor](q *Query, items []T) ([]T, error) {
+	opts, err := q.ParseOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	Sort(items, opts.Order)
+	return Paginate(items, opts), nil
+}
@@ -0,0 +1,179 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+type testRecord map[string]any
+
+func (r testRecord) Get(key string) any {
+	var value any = map[string]any(r)
+	for _, k := range strings.Split(key, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[k]
+		case []any:
+			idx, err := strconv.Atoi(k)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil
+			}
+			value = v[idx]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+func recordIds(records []testRecord) []any {
+	ids := make([]any, len(records))
+	for i, r := range records {
+		ids[i] = r["id"]
+	}
+	return ids
+}
+
+func TestApplyOptions(t *testing.T) {
+	records := func() []testRecord {
+		return []testRecord{
+			{"id": "c", "rank": 2, "title": "Charlie"},
+			{"id": "a", "rank": 1, "title": "Alpha"},
+			{"id": "d", "rank": 2, "title": "Delta"},
+			{"id": "b", "rank": 3},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []any
+	}{
+		{"Default order", "{limit:10}", []any{"a", "b", "c", "d"}},
+		{"Order descending", `{order:["id","desc"]}`, []any{"d", "c", "b", "a"}},
+		{"Multi-key order", `{order:["rank","desc","title","desc"]}`, []any{"b", "d", "c", "a"}},
+		{"Order pairs", `{order:[["rank"],["id","desc"]]}`, []any{"a", "d", "c", "b"}},
+		{"Missing values first", `{order:["title"]}`, []any{"b", "a", "c", "d"}},
+		{"Limit and offset", `{limit:2, offset:1}`, []any{"b", "c"}},
+		{"Offset out of range", `{offset:10}`, []any{}},
+		{"Filter with options", `gte(rank 2, {order:["id","desc"], limit:2})`, []any{"d", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseFromString(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			matched := []testRecord{}
+			for _, r := range records() {
+				if q.Match(r) {
+					matched = append(matched, r)
+				}
+			}
+
+			result, err := Apply(q, matched)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(recordIds(result), tt.expected); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("Invalid options", func(t *testing.T) {
+		for _, rawQuery := range []string{`{limit:-1}`, `{offset:"a"}`, `{order:["desc"]}`, `{order:1}`} {
+			q, err := ParseFromString(rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Apply(q, records()); err == nil {
+				t.Errorf("Expected error for %s", rawQuery)
+			}
+		}
+	})
+
+	t.Run("Without pagination", func(t *testing.T) {
+		q, _ := ParseFromString(`{limit:1, offset:1, order:["id"]}`)
+		expected := map[string]any{"order": []any{"id"}}
+		if diff := deep.Equal(q.WithoutPagination().Options, expected); diff != nil {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	records := []testRecord{
+		{"id": "a", "author": "jane", "tags": []any{"go", "cms"}},
+		{"id": "b", "author": "john", "tags": []any{"go"}},
+		{"id": "c", "author": "jane", "tags": []any{"rust", "go"}},
+		{"id": "d"},
+	}
+
+	q, err := ParseFromString(`{count:true, distinct:"author", group:["tags","author"], limit:1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := q.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"count": 4,
+		"distinct": map[string][]any{
+			"author": {"jane", "john"},
+		},
+		"group": map[string][]GroupCount{
+			"tags":   {{"go", 3}, {"cms", 1}, {"rust", 1}},
+			"author": {{"jane", 2}, {"john", 1}},
+		},
+	}
+
+	if diff := deep.Equal(Aggregate(opts, records), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if diff := deep.Equal(q.WithoutAggregations().Options, map[string]any(nil)); diff != nil {
+		t.Error(diff)
+	}
+
+	t.Run("Invalid aggregations", func(t *testing.T) {
+		for _, rawQuery := range []string{`{count:1}`, `{distinct:1}`, `{group:[1]}`, `{select:[""]}`} {
+			q, err := ParseFromString(rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := q.Validate(); err == nil {
+				t.Errorf("Expected %s to be invalid", rawQuery)
+			}
+		}
+	})
+}
+
+func TestProject(t *testing.T) {
+	record := testRecord{
+		"id":    "a",
+		"title": "Hello",
+		"body":  "Long body",
+		"meta":  map[string]any{"date": "2024-01-01", "draft": false},
+	}
+
+	expected := map[string]any{
+		"title": "Hello",
+		"meta":  map[string]any{"date": "2024-01-01"},
+	}
+
+	if diff := deep.Equal(Project(record, []string{"title", "meta.date", "missing"}), expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"text/scanner"
 
@@ -61,7 +62,7 @@ func (p *Parser) Next() *Token {
 		p.nextNextToken = &Token{
 			Text:     p.sc.TokenText(),
 			Raw:      nextTok,
-			Position: p.sc.Pos(),
+			Position: p.sc.Position,
 		}
 	} else {
 		p.nextNextToken = nil
@@ -92,7 +93,27 @@ func (p *Parser) Parse(input string) (*Query, error) {
 	p.Next()
 
 	defer p.log("===== end of parsing =====")
-	return p.parseQuery()
+
+	var query *Query
+	var err error
+
+	if p.nextToken != nil && p.nextToken.Raw == '{' {
+		// options-only query (eg. {limit:10})
+		query = &Query{}
+		query.Options, err = p.parseQueryOptions()
+	} else {
+		query, err = p.parseQuery()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.nextToken != nil && p.nextToken.Raw != scanner.EOF {
+		return nil, p.errorAt(p.nextToken, nil, "unexpected %s after end of query", p.nextToken.Text)
+	}
+
+	return query, nil
 }
 
 func (p *Parser) log(f string, a ...any) {
@@ -105,12 +126,28 @@ func (p *Parser) log(f string, a ...any) {
 	fmt.Printf(f, a...)
 }
 
-var validQueryValues = []rune{'[', '{', scanner.String, scanner.Int, scanner.Float}
+var validQueryValues = []rune{'[', '{', '-', '$', scanner.String, scanner.Int, scanner.Float}
+
+var literalQueryValues = map[string]any{
+	"true":  true,
+	"false": false,
+	"null":  nil,
+}
+
+func isQueryValue(tok *Token) bool {
+	if tok == nil {
+		return false
+	} else if tok.Raw == scanner.Ident {
+		_, ok := literalQueryValues[tok.Text]
+		return ok
+	}
+	return slices.Contains(validQueryValues, tok.Raw)
+}
 
 func (p *Parser) parseQuery() (*Query, error) {
 	token := p.Next()
-	if token.Raw != scanner.Ident {
-		return nil, fmt.Errorf("expected operator or field, got %s", token.Text)
+	if token == nil || token.Raw != scanner.Ident {
+		return nil, p.expectedError(token, "operator", "field")
 	}
 
 	query := &Query{
@@ -120,7 +157,7 @@ func (p *Parser) parseQuery() (*Query, error) {
 
 	field := p.currentToken.Text
 	// support dot notation-like field
-	for p.nextToken.Raw == '.' || (len(p.nextToken.Text) > 1 && p.nextToken.Text[0] == '.') {
+	for p.nextToken != nil && (p.nextToken.Raw == '.' || (len(p.nextToken.Text) > 1 && p.nextToken.Text[0] == '.')) {
 		tok := p.Next() // .
 		if len(tok.Text) > 1 {
 			field += tok.Text
@@ -130,7 +167,9 @@ func (p *Parser) parseQuery() (*Query, error) {
 		}
 	}
 
-	if p.nextToken.Raw == '(' {
+	if p.nextToken == nil {
+		return nil, p.expectedError(nil, "(", "value")
+	} else if p.nextToken.Raw == '(' {
 		// Parse nested query entries
 		queries, err := p.parseNestedQueries(query)
 		if err != nil {
@@ -140,7 +179,23 @@ func (p *Parser) parseQuery() (*Query, error) {
 		if len(queries) > 0 {
 			query.Value = queries
 		}
-	} else if slices.Contains(validQueryValues, p.nextToken.Raw) {
+	} else if p.parentQuery != nil && p.nextToken.Raw == ')' {
+		// Query entry without a value (eg. isnull(field))
+		query = p.parentQuery
+		query.Field = field
+		return query, nil
+	} else if p.parentQuery != nil && p.nextToken.Raw == scanner.Ident && p.nextNextToken != nil && p.nextNextToken.Raw == '(' {
+		// Query entry with a nested query as value (eg. elem(authors eq(role "editor")))
+		query = p.parentQuery
+		value, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+
+		query.Field = field
+		query.Value = value
+		return query, nil
+	} else if p.parentQuery != nil && isQueryValue(p.nextToken) {
 		// Parse query entry
 		query = p.parentQuery
 		value, err := p.parseJSONValue()
@@ -152,14 +207,16 @@ func (p *Parser) parseQuery() (*Query, error) {
 		query.Value = value
 
 		return query, nil
-	} else if _, ok := supportedOperators[Operator(token.Text)]; !ok {
-		return nil, fmt.Errorf("unsupported operator: %s", token.Text)
+	} else if p.parentQuery == nil {
+		return nil, p.expectedError(p.nextToken, "(")
 	} else {
-		return nil, fmt.Errorf("expected '(' or ',', got %s", token.Text)
+		return nil, p.expectedError(p.nextToken, "(", "value")
 	}
 
-	if p.nextToken != nil {
-		if p.nextToken.Raw == ',' && p.nextNextToken.Raw == '{' {
+	if p.nextToken != nil && p.nextToken.Raw == ',' {
+		if p.nextNextToken == nil {
+			return nil, p.endOfQueryError()
+		} else if p.nextNextToken.Raw == '{' {
 			p.Next() // ,
 			options, err := p.parseQueryOptions()
 			if err != nil {
@@ -172,8 +229,8 @@ func (p *Parser) parseQuery() (*Query, error) {
 	token = p.Next()
 	token.debug("expected end parseQuery")
 
-	if token.Raw != ')' {
-		return nil, expectedError(')', token)
+	if token == nil || token.Raw != ')' {
+		return nil, p.expectedError(token, ")")
 	}
 
 	return query, nil
@@ -191,11 +248,11 @@ func (p *Parser) parseNestedQueries(parent *Query) ([]*Query, error) {
 	var queries []*Query
 
 	token := p.Next()
-	if token.Raw != '(' {
-		return nil, expectedError('(', token)
+	if token == nil || token.Raw != '(' {
+		return nil, p.expectedError(token, "(")
 	}
 
-	for p.nextToken.Raw != ')' {
+	for p.nextToken != nil && p.nextToken.Raw != ')' {
 		query, err := p.parseQuery()
 		if err != nil {
 			return nil, err
@@ -205,6 +262,8 @@ func (p *Parser) parseNestedQueries(parent *Query) ([]*Query, error) {
 		}
 		if p.nextToken == nil || p.nextToken.Raw != ',' || p.nextToken.Raw == ')' {
 			break
+		} else if p.nextNextToken == nil {
+			return nil, p.endOfQueryError()
 		} else if p.nextNextToken.Raw == '{' {
 			p.log("break!")
 			break
@@ -215,11 +274,13 @@ func (p *Parser) parseNestedQueries(parent *Query) ([]*Query, error) {
 
 	// parse query options in the parent query
 	if p.nextToken != nil {
-		if p.nextToken.Raw == ',' && p.nextNextToken.Raw == '{' {
+		if p.nextToken.Raw == ',' && p.nextNextToken == nil {
+			return nil, p.endOfQueryError()
+		} else if p.nextToken.Raw == ',' && p.nextNextToken.Raw == '{' {
 			p.log("end of nested queries, expecting parsing options")
 			return queries, nil
 		} else if p.nextToken.Raw != ')' {
-			return nil, expectedError(')', token)
+			return nil, p.expectedError(p.nextToken, ")", ",")
 		}
 	}
 
@@ -235,32 +296,34 @@ func (p *Parser) parseQueryOptions() (map[string]any, error) {
 	options := make(map[string]any)
 
 	token := p.Next()
-	if token.Raw != '{' {
-		return nil, expectedError('{', token)
+	if token == nil || token.Raw != '{' {
+		return nil, p.expectedError(token, "{")
 	}
 
 	i := 0
 
-	for p.nextToken.Raw != '}' {
+	for p.nextToken != nil && p.nextToken.Raw != '}' {
 		token = p.Next()
 		token.debug("loop inside parseQueryOptions")
 
 		if i > 0 {
-			if token.Raw != ',' {
-				return nil, expectedError(',', token)
+			if token == nil || token.Raw != ',' {
+				return nil, p.expectedError(token, ",")
 			}
 			token = p.Next()
 		}
 
-		if token.Raw != scanner.Ident {
-			return nil, fmt.Errorf("expected option key, got %s", token.Text)
+		if token == nil {
+			return nil, p.expectedError(token, "option key")
+		} else if token.Raw != scanner.Ident {
+			return nil, p.expectedError(token, "option key")
 		}
 
 		key := token.Text
 
 		token = p.Next()
-		if token.Raw != ':' {
-			return nil, expectedError(':', token)
+		if token == nil || token.Raw != ':' {
+			return nil, p.expectedError(token, ":")
 		}
 
 		value, err := p.parseJSONValue()
@@ -274,8 +337,8 @@ func (p *Parser) parseQueryOptions() (map[string]any, error) {
 	}
 
 	token = p.Next()
-	if token.Raw != '}' {
-		return nil, expectedError('}', token)
+	if token == nil || token.Raw != '}' {
+		return nil, p.expectedError(token, "}")
 	}
 
 	return options, nil
@@ -283,7 +346,9 @@ func (p *Parser) parseQueryOptions() (map[string]any, error) {
 
 func (p *Parser) parseJSONValue() (any, error) {
 	token := p.nextToken
-	if p.nextToken.Raw != '{' {
+	if token == nil {
+		return nil, p.expectedError(nil, "value")
+	} else if p.nextToken.Raw != '{' {
 		p.Next()
 	}
 
@@ -291,14 +356,30 @@ func (p *Parser) parseJSONValue() (any, error) {
 
 	switch token.Raw {
 	case scanner.String:
-		value := token.Text
-		// Remove the surrounding quotes from the value
-		value = value[1 : len(value)-1]
+		value, err := strconv.Unquote(token.Text)
+		if err != nil {
+			// Remove the surrounding quotes from the value
+			value = token.Text[1 : len(token.Text)-1]
+		}
 		return value, nil
 	case scanner.Int, scanner.Float:
-		var value json.Number
-		if err := json.NewDecoder(strings.NewReader(token.Text)).Decode(&value); err != nil {
-			return nil, err
+		return decodeJSONNumber(token.Text)
+	case '-':
+		token = p.Next()
+		if token == nil || (token.Raw != scanner.Int && token.Raw != scanner.Float) {
+			return nil, p.expectedError(token, "number")
+		}
+		return decodeJSONNumber("-" + token.Text)
+	case '$':
+		nameToken := p.Next()
+		if nameToken == nil || nameToken.Raw != scanner.Ident || nameToken.Position.Offset != token.Position.Offset+1 {
+			return nil, p.expectedError(nameToken, "parameter name")
+		}
+		return Placeholder(nameToken.Text), nil
+	case scanner.Ident:
+		value, ok := literalQueryValues[token.Text]
+		if !ok {
+			return nil, p.expectedError(token, "value")
 		}
 		return value, nil
 	case '{':
@@ -306,28 +387,100 @@ func (p *Parser) parseJSONValue() (any, error) {
 		return p.parseQueryOptions()
 	case '[':
 		// Parse JSON array
+		arrayToken := token
 		accumulateText := &strings.Builder{}
-		for token.Raw != ']' {
-			accumulateText.WriteString(token.Text)
-			token = p.Next()
+		depth := 0
+		for {
+			if token == nil || token.Raw == scanner.EOF {
+				return nil, p.expectedError(nil, "]")
+			}
 
-			if token.Raw == scanner.EOF {
-				return nil, fmt.Errorf("unexpected end of input")
+			accumulateText.WriteString(token.Text)
+			if token.Raw == '[' {
+				depth++
 			} else if token.Raw == ']' {
-				accumulateText.WriteString(token.Text)
+				depth--
+			}
+
+			if depth == 0 {
 				break
 			}
+			token = p.Next()
 		}
+
 		var arr []any
-		if err := json.NewDecoder(strings.NewReader(accumulateText.String())).Decode(&arr); err != nil {
-			return nil, err
+		decoder := json.NewDecoder(strings.NewReader(accumulateText.String()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&arr); err != nil {
+			return nil, p.errorAt(arrayToken, nil, "invalid array: %s", err.Error())
 		}
-		return arr, nil
+		return decodePlaceholders(arr), nil
 	default:
-		return nil, fmt.Errorf("invalid JSON value: %s", token.Text)
+		return nil, p.expectedError(token, "value")
+	}
+}
+
+func decodeJSONNumber(text string) (json.Number, error) {
+	var value json.Number
+	if err := json.NewDecoder(strings.NewReader(text)).Decode(&value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// ParseError is returned when a query cannot be parsed
+type ParseError struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Offset   int      `json:"offset"`
+	Token    string   `json:"token"`
+	Expected []string `json:"expected,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", err.Line, err.Column, err.Message)
+}
+
+// errorAt creates a parse error located at the token. A nil token
+// refers to the end of the input.
+func (p *Parser) errorAt(tok *Token, expected []string, f string, a ...any) *ParseError {
+	err := &ParseError{
+		Expected: expected,
+		Message:  fmt.Sprintf(f, a...),
+	}
+
+	pos := p.sc.Pos()
+	if tok != nil && tok.Raw != scanner.EOF {
+		pos = tok.Position
+		err.Token = tok.Text
 	}
+
+	err.Line = pos.Line
+	err.Column = pos.Column
+	err.Offset = pos.Offset
+	return err
 }
 
-func expectedError(exp rune, tok *Token) error {
-	return fmt.Errorf("expected %q, got %s", exp, tok.Text)
+// endOfQueryError is returned when the input ends after a separator
+func (p *Parser) endOfQueryError() *ParseError {
+	return p.errorAt(nil, nil, "unexpected end of query")
+}
+
+func (p *Parser) expectedError(tok *Token, expected ...string) *ParseError {
+	descriptions := make([]string, len(expected))
+	for i, exp := range expected {
+		if len(exp) == 1 {
+			descriptions[i] = "'" + exp + "'"
+		} else {
+			descriptions[i] = exp
+		}
+	}
+
+	got := "end of input"
+	if tok != nil && tok.Raw != scanner.EOF {
+		got = tok.Text
+	}
+
+	return p.errorAt(tok, expected, "expected %s, got %s", strings.Join(descriptions, " or "), got)
 }
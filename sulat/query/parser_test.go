@@ -208,3 +208,152 @@ func TestParser(t *testing.T) {
 		}
 	})
 }
+
+func TestQueryStringRoundTrip(t *testing.T) {
+	queries := []string{
+		"eq(id 1)",
+		"eq(name \"John \\\"JD\\\" Doe\")",
+		"gt(price -5.25)",
+		"eq(published true)",
+		"isnull(deleted_at)",
+		"in(id [1,2,[3,4]])",
+		"eq(a.b.1.c.0 1)",
+		"and(eq(id 1),eq(name \"John Doe\"),{limit:10, offset:0, order:[\"id\",\"desc\"]})",
+		"and(eq(id 1),eq(name \"John Doe\"),or(eq(id 1),eq(name \"John Doe\")))",
+		"eq(id 1, {limit:10, custom: {test: 123, deep: {hello:\"world\"}}})",
+		"elem(authors and(eq(role \"editor\"),gt(posts 2)))",
+		"and(contains(tags \"go\"),elem(scores gt(_ 90)),size(tags 2))",
+		"eq(a {x:1})",
+		"eq(meta {author: {name:\"John\"}, tags:[\"go\"]}, {limit:1})",
+		"contains(items {id:$id})",
+	}
+
+	for _, rawQuery := range queries {
+		t.Run(rawQuery, func(t *testing.T) {
+			parsed, err := ParseFromString(rawQuery)
+			if err != nil {
+				t.Fatalf("Error parsing query: %v", err)
+			}
+
+			reparsed, err := ParseFromString(parsed.String())
+			if err != nil {
+				t.Fatalf("Error parsing stringified query %q: %v", parsed.String(), err)
+			}
+
+			if diff := deep.Equal(reparsed, parsed); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParseFromString(t *testing.T) {
+	t.Run("Empty query", func(t *testing.T) {
+		result, err := ParseFromString("")
+		if err != nil {
+			t.Fatal(err)
+		} else if result != nil {
+			t.Fatalf("Expected nil query, got %s", result)
+		}
+	})
+
+	t.Run("Literal values", func(t *testing.T) {
+		expected := &Query{
+			Operator: "and",
+			Value: []*Query{
+				{Operator: "eq", Field: "published", Value: true},
+				{Operator: "gt", Field: "price", Value: json.Number("-5")},
+				{Operator: "eq", Field: "title", Value: "Say \"hi\""},
+			},
+		}
+
+		result, err := ParseFromString(`and(eq(published true),gt(price -5),eq(title "Say \"hi\""))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("Trailing input", func(t *testing.T) {
+		if _, err := ParseFromString("eq(id 1) eq(id 2)"); err == nil {
+			t.Fatal("Expected error for trailing input")
+		}
+	})
+
+	t.Run("Unterminated query", func(t *testing.T) {
+		if _, err := ParseFromString("and(eq(id 1)"); err == nil {
+			t.Fatal("Expected error for unterminated query")
+		}
+	})
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected *ParseError
+	}{
+		{
+			query: "and(eq(id 1) eq(id 2))",
+			expected: &ParseError{
+				Line: 1, Column: 14, Offset: 13, Token: "eq",
+				Expected: []string{")", ","},
+				Message:  "expected ')' or ',', got eq",
+			},
+		},
+		{
+			query: "and(\n  eq(id 1),\n  gt(views x)\n)",
+			expected: &ParseError{
+				Line: 3, Column: 12, Offset: 28, Token: "x",
+				Expected: []string{"(", "value"},
+				Message:  "expected '(' or value, got x",
+			},
+		},
+		{
+			query: "eq(id 1, {limit 10})",
+			expected: &ParseError{
+				Line: 1, Column: 17, Offset: 16, Token: "10",
+				Expected: []string{":"},
+				Message:  "expected ':', got 10",
+			},
+		},
+		{
+			query: "in(id [1,2",
+			expected: &ParseError{
+				Line: 1, Column: 11, Offset: 10,
+				Expected: []string{"]"},
+				Message:  "expected ']', got end of input",
+			},
+		},
+		{
+			query: "and(eq(id 1),",
+			expected: &ParseError{
+				Line: 1, Column: 14, Offset: 13,
+				Message: "unexpected end of query",
+			},
+		},
+		{
+			query: "eq(id 1,",
+			expected: &ParseError{
+				Line: 1, Column: 9, Offset: 8,
+				Message: "unexpected end of query",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseFromString(tt.query)
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Expected a *ParseError, got %v", err)
+			}
+
+			if diff := deep.Equal(parseErr, tt.expected); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// Placeholder is a named value of a query (eg. $author) which is
+// supplied later through Query.Bind
+type Placeholder string
+
+// placeholderKey is the object key used by the JSON representation
+// of a placeholder (eg. {"$param":"author"})
+const placeholderKey = "$param"
+
+func (p Placeholder) String() string {
+	return "$" + string(p)
+}
+
+func (p Placeholder) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{placeholderKey: string(p)})
+}
+
+// decodePlaceholders replaces the JSON representation of the
+// placeholders found in the value into a Placeholder
+func decodePlaceholders(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if name, ok := v[placeholderKey].(string); ok && len(v) == 1 {
+			return Placeholder(name)
+		}
+		for key, item := range v {
+			v[key] = decodePlaceholders(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = decodePlaceholders(item)
+		}
+	}
+	return value
+}
+
+// Placeholders returns the names of the placeholders used in the query
+// and its options
+func (q *Query) Placeholders() []string {
+	var names []string
+	collect := func(value any) bool {
+		if p, ok := value.(Placeholder); ok && !slices.Contains(names, string(p)) {
+			names = append(names, string(p))
+		}
+		return true
+	}
+
+	q.walkValues(collect)
+	return names
+}
+
+// walkValues calls the function on every value of the query tree
+// including the option values. Walking stops once the function
+// returns false.
+func (q *Query) walkValues(fn func(value any) bool) bool {
+	if q == nil {
+		return true
+	}
+
+	switch v := q.Value.(type) {
+	case []*Query:
+		for _, query := range v {
+			if !query.walkValues(fn) {
+				return false
+			}
+		}
+	case *Query:
+		if !v.walkValues(fn) {
+			return false
+		}
+	default:
+		if !walkValue(v, fn) {
+			return false
+		}
+	}
+
+	for _, value := range q.Options {
+		if !walkValue(value, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func walkValue(value any, fn func(value any) bool) bool {
+	if !fn(value) {
+		return false
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, item := range v {
+			if !walkValue(item, fn) {
+				return false
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if !walkValue(item, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// HasPlaceholders checks if the query still has unbound placeholders
+func (q *Query) HasPlaceholders() bool {
+	return !q.walkValues(func(value any) bool {
+		_, ok := value.(Placeholder)
+		return !ok
+	})
+}
+
+// Bind returns a copy of the query with its placeholders replaced
+// by the given values. An error is returned if a placeholder has
+// no value.
+func (q *Query) Bind(params map[string]any) (*Query, error) {
+	if q == nil {
+		return nil, nil
+	}
+
+	newQuery := &Query{
+		Field:    q.Field,
+		Operator: q.Operator,
+	}
+
+	switch v := q.Value.(type) {
+	case []*Query:
+		queries := make([]*Query, len(v))
+		for i, query := range v {
+			bound, err := query.Bind(params)
+			if err != nil {
+				return nil, err
+			}
+			queries[i] = bound
+		}
+		newQuery.Value = queries
+	case *Query:
+		bound, err := v.Bind(params)
+		if err != nil {
+			return nil, err
+		}
+		newQuery.Value = bound
+	default:
+		value, err := bindValue(v, params)
+		if err != nil {
+			return nil, err
+		}
+		newQuery.Value = value
+	}
+
+	if q.Options != nil {
+		options, err := bindValue(q.Options, params)
+		if err != nil {
+			return nil, err
+		}
+		newQuery.Options = options.(map[string]any)
+	}
+
+	return newQuery, nil
+}
+
+func bindValue(value any, params map[string]any) (any, error) {
+	switch v := value.(type) {
+	case Placeholder:
+		bound, ok := params[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("missing value for parameter %q", string(v))
+		}
+		return bound, nil
+	case map[string]any:
+		newMap := make(map[string]any, len(v))
+		for key, item := range v {
+			bound, err := bindValue(item, params)
+			if err != nil {
+				return nil, err
+			}
+			newMap[key] = bound
+		}
+		return newMap, nil
+	case []any:
+		newSlice := make([]any, len(v))
+		for i, item := range v {
+			bound, err := bindValue(item, params)
+			if err != nil {
+				return nil, err
+			}
+			newSlice[i] = bound
+		}
+		return newSlice, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestPlaceholders(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		result, err := ParseFromString(`and(eq(author $author),in(tags $tags),{limit:$limit})`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := &Query{
+			Operator: OpAnd,
+			Value: []*Query{
+				{Operator: OpEq, Field: "author", Value: Placeholder("author")},
+				{Operator: OpIn, Field: "tags", Value: Placeholder("tags")},
+			},
+			Options: map[string]any{"limit": Placeholder("limit")},
+		}
+
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Error(diff)
+		}
+
+		if diff := deep.Equal(result.Placeholders(), []string{"author", "tags", "limit"}); diff != nil {
+			t.Error(diff)
+		}
+
+		if err := result.Validate(); err != nil {
+			t.Errorf("Expected unbound query to be valid, got %v", err)
+		}
+	})
+
+	t.Run("Invalid name", func(t *testing.T) {
+		for _, rawQuery := range []string{`eq(author $)`, `eq(author $ author)`, `eq(author $"author")`} {
+			if _, err := ParseFromString(rawQuery); err == nil {
+				t.Errorf("Expected error for %s", rawQuery)
+			}
+		}
+	})
+
+	t.Run("String and JSON", func(t *testing.T) {
+		parsed, err := ParseFromString(`elem(authors eq(name $name),{limit:$limit})`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed.String() != `elem(authors eq(name $name),{limit:$limit})` {
+			t.Errorf("Unexpected string form: %s", parsed.String())
+		}
+
+		encoded, err := json.Marshal(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedJSON := `{"operator":"elem","field":"authors","value":{"operator":"eq","field":"name","value":{"$param":"name"}},"options":{"limit":{"$param":"limit"}}}`
+		if string(encoded) != expectedJSON {
+			t.Errorf("Expected %s, got %s", expectedJSON, encoded)
+		}
+
+		decoded, err := ParseFromJSON(encoded)
+		if err != nil {
+			t.Fatal(err)
+		} else if diff := deep.Equal(decoded, parsed); diff != nil {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("Bind", func(t *testing.T) {
+		parsed, err := ParseFromString(`and(eq(author $author),gt(views $views),{limit:$limit,order:["views","desc"]})`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bound, err := parsed.Bind(map[string]any{
+			"author": "jane",
+			"views":  json.Number("10"),
+			"limit":  json.Number("5"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, _ := ParseFromString(`and(eq(author "jane"),gt(views 10),{limit:5,order:["views","desc"]})`)
+		if diff := deep.Equal(bound, expected); diff != nil {
+			t.Error(diff)
+		}
+
+		if bound.HasPlaceholders() {
+			t.Error("Expected bound query to have no placeholders")
+		} else if !parsed.HasPlaceholders() {
+			t.Error("Expected original query to be left unbound")
+		}
+
+		if _, err := parsed.Bind(map[string]any{"author": "jane"}); err == nil {
+			t.Error("Expected error for missing parameters")
+		}
+	})
+}
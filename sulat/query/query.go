@@ -5,6 +5,9 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type Operator string
@@ -22,7 +25,11 @@ func (op Operator) IsIn() bool {
 }
 
 func (op Operator) IsLike() bool {
-	return op == OpLike || op == OpNlike
+	return op == OpLike || op == OpNlike || op == OpIlike || op == OpNilike
+}
+
+func (op Operator) IsText() bool {
+	return op.IsLike() || op == OpStartsWith || op == OpEndsWith || op == OpRegex
 }
 
 func (op Operator) IsNull() bool {
@@ -33,27 +40,41 @@ func (op Operator) IsBetween() bool {
 	return op == OpBetween || op == OpNbetween
 }
 
+func (op Operator) IsArray() bool {
+	return op == OpContains || op == OpContainsAny || op == OpContainsAll || op == OpSize || op == OpElem
+}
+
 func (op Operator) IsSupported() bool {
 	return supportedOperators[op]
 }
 
 const (
-	OpAnd      Operator = "and"      // and
-	OpOr       Operator = "or"       // or
-	OpEq       Operator = "eq"       // equal to
-	OpNeq      Operator = "neq"      // not equal to
-	OpGt       Operator = "gt"       // greater than
-	OpGte      Operator = "gte"      // greater than or equal to
-	OpLt       Operator = "lt"       // less than
-	OpLte      Operator = "lte"      // less than or equal to
-	OpIn       Operator = "in"       // in
-	OpNin      Operator = "nin"      // not in
-	OpLike     Operator = "like"     // like
-	OpNlike    Operator = "nlike"    // not like
-	OpIsnull   Operator = "isnull"   // is null
-	OpNotnull  Operator = "notnull"  // is not null
-	OpBetween  Operator = "between"  // between
-	OpNbetween Operator = "nbetween" // not between
+	OpAnd         Operator = "and"         // and
+	OpOr          Operator = "or"          // or
+	OpEq          Operator = "eq"          // equal to
+	OpNeq         Operator = "neq"         // not equal to
+	OpGt          Operator = "gt"          // greater than
+	OpGte         Operator = "gte"         // greater than or equal to
+	OpLt          Operator = "lt"          // less than
+	OpLte         Operator = "lte"         // less than or equal to
+	OpIn          Operator = "in"          // in
+	OpNin         Operator = "nin"         // not in
+	OpLike        Operator = "like"        // like
+	OpNlike       Operator = "nlike"       // not like
+	OpIlike       Operator = "ilike"       // case-insensitive like
+	OpNilike      Operator = "nilike"      // case-insensitive not like
+	OpStartsWith  Operator = "startswith"  // starts with
+	OpEndsWith    Operator = "endswith"    // ends with
+	OpRegex       Operator = "regex"       // matches regular expression
+	OpContains    Operator = "contains"    // array contains value
+	OpContainsAny Operator = "containsany" // array contains any of the values
+	OpContainsAll Operator = "containsall" // array contains all of the values
+	OpSize        Operator = "size"        // array has size
+	OpElem        Operator = "elem"        // any array element matches query
+	OpIsnull      Operator = "isnull"      // is null
+	OpNotnull     Operator = "notnull"     // is not null
+	OpBetween     Operator = "between"     // between
+	OpNbetween    Operator = "nbetween"    // not between
 )
 
 // a Query is a collection of conditions
@@ -65,15 +86,24 @@ type Query struct {
 }
 
 func (q *Query) Match(data Accessor) bool {
-	if q.Operator.IsLogical() {
+	if len(q.Operator) == 0 {
+		// options-only queries match everything
+		return true
+	} else if q.Operator.IsLogical() {
 		return q.matchLogical(data)
 	}
-	return matchers[q.Operator](q, data)
+
+	matcher, ok := matchers[q.Operator]
+	if !ok {
+		return false
+	}
+	return matcher(q, data)
 }
 
 func (q *Query) matchLogical(data Accessor) bool {
+	queries, _ := q.Value.([]*Query)
 	if q.Operator == OpAnd {
-		for _, query := range q.Value.([]*Query) {
+		for _, query := range queries {
 			if !query.Match(data) {
 				return false
 			}
@@ -81,7 +111,7 @@ func (q *Query) matchLogical(data Accessor) bool {
 		return true
 	}
 
-	for _, query := range q.Value.([]*Query) {
+	for _, query := range queries {
 		if query.Match(data) {
 			return true
 		}
@@ -89,62 +119,119 @@ func (q *Query) matchLogical(data Accessor) bool {
 	return false
 }
 
+// String returns the textual representation of the query which can
+// be parsed back with ParseFromString
 func (q *Query) String() string {
 	sb := &strings.Builder{}
+	q.writeTo(sb)
+	return sb.String()
+}
+
+func (q *Query) writeTo(sb *strings.Builder) {
+	if len(q.Operator) == 0 {
+		if q.Options != nil {
+			stringifyQueryOptions(q.Options, sb)
+		}
+		return
+	}
+
 	sb.WriteString(string(q.Operator) + "(")
-	if !q.Operator.IsLogical() {
-		sb.WriteString(q.Field + " ")
+	hasEntries := true
+	if q.Operator.IsLogical() {
+		queries, _ := q.Value.([]*Query)
+		for i, query := range queries {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			query.writeTo(sb)
+		}
+		hasEntries = len(queries) > 0
+	} else {
+		sb.WriteString(q.Field)
+		if subQuery, ok := q.Value.(*Query); ok {
+			sb.WriteByte(' ')
+			subQuery.writeTo(sb)
+		} else if q.Value != nil {
+			sb.WriteByte(' ')
+			stringifyQueryValue(q.Value, sb)
+		}
 	}
-	json.NewEncoder(sb).Encode(q.Value)
 	if q.Options != nil {
-		sb.WriteByte(',')
+		if hasEntries {
+			sb.WriteByte(',')
+		}
 		stringifyQueryOptions(q.Options, sb)
 	}
 	sb.WriteString(")")
-	return sb.String()
+}
+
+func stringifyQueryValue(value any, sb *strings.Builder) {
+	if placeholder, ok := value.(Placeholder); ok {
+		sb.WriteString(placeholder.String())
+		return
+	} else if mp, ok := value.(map[string]any); ok {
+		// objects use the same syntax as the options
+		stringifyQueryOptions(mp, sb)
+		return
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		sb.WriteString("null")
+		return
+	}
+	sb.Write(encoded)
 }
 
 func stringifyQueryOptions(options map[string]any, sb *strings.Builder) {
+	keys := maps.Keys(options)
+	slices.Sort(keys)
+
 	sb.WriteString("{")
-	i := 0
-	for k, v := range options {
-		sb.WriteString(k + ":")
-		if mp, ok := v.(map[string]any); ok {
-			stringifyQueryOptions(mp, sb)
-		} else {
-			json.NewEncoder(sb).Encode(v)
-		}
-		if i < len(options)-1 {
+	for i, k := range keys {
+		if i > 0 {
 			sb.WriteString(",")
 		}
-		i++
+		sb.WriteString(k + ":")
+		stringifyQueryValue(options[k], sb)
 	}
 	sb.WriteString("}")
 }
 
 var supportedOperators = map[Operator]bool{
-	OpAnd:      true,
-	OpOr:       true,
-	OpEq:       true,
-	OpNeq:      true,
-	OpGt:       true,
-	OpGte:      true,
-	OpLt:       true,
-	OpLte:      true,
-	OpIn:       true,
-	OpNin:      true,
-	OpLike:     true,
-	OpNlike:    true,
-	OpIsnull:   true,
-	OpNotnull:  true,
-	OpBetween:  true,
-	OpNbetween: true,
-}
-
-// ParseFromRequest parses a query from a request
+	OpAnd:         true,
+	OpOr:          true,
+	OpEq:          true,
+	OpNeq:         true,
+	OpGt:          true,
+	OpGte:         true,
+	OpLt:          true,
+	OpLte:         true,
+	OpIn:          true,
+	OpNin:         true,
+	OpLike:        true,
+	OpNlike:       true,
+	OpIlike:       true,
+	OpNilike:      true,
+	OpStartsWith:  true,
+	OpEndsWith:    true,
+	OpRegex:       true,
+	OpContains:    true,
+	OpContainsAny: true,
+	OpContainsAll: true,
+	OpSize:        true,
+	OpElem:        true,
+	OpIsnull:      true,
+	OpNotnull:     true,
+	OpBetween:     true,
+	OpNbetween:    true,
+}
+
+// ParseFromRequest parses a query from a request. A request without
+// a query returns a nil query which matches all records.
 func ParseFromRequest(r *http.Request) (*Query, error) {
 	if !r.URL.Query().Has("q") {
-		return nil, errors.New("no query found in request")
+		return nil, nil
 	}
 
 	rawQueryValue := r.URL.Query().Get("q")
@@ -157,10 +244,9 @@ func ParseFromRequest(r *http.Request) (*Query, error) {
 
 // ParseFromString parses a query from a string
 func ParseFromString(rawQuery string) (*Query, error) {
-	query := &Query{}
-	if len(rawQuery) == 0 {
-		return query, nil
+	if len(strings.TrimSpace(rawQuery)) == 0 {
+		return nil, nil
 	}
 
-	return query, nil
+	return NewParser().Parse(rawQuery)
 }
@@ -0,0 +1,590 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotCompilable is returned when a query (or a part of it) cannot be
+// translated into SQL. Callers are expected to fall back to matching the
+// records in memory with Query.Match.
+var ErrNotCompilable = errors.New("query cannot be compiled to SQL")
+
+// SQLCompiler translates queries into parameterized SQLite clauses for
+// records stored as JSON documents.
+type SQLCompiler struct {
+	// IdColumn is the column where the record id is stored. Defaults to "id".
+	IdColumn string
+
+	// DataColumn is the column where the record data is stored as a
+	// JSON document. Defaults to "data".
+	DataColumn string
+
+	// Columns maps top-level fields to dedicated columns
+	Columns map[string]string
+
+	// elemDepth is the nesting level of elem queries used for
+	// naming the json_each aliases
+	elemDepth int
+
+	// elemAlias is the json_each alias of the element being compiled
+	elemAlias string
+}
+
+// SQLQuery is the compiled form of a query
+type SQLQuery struct {
+	Where   string
+	Args    []any
+	OrderBy string
+	Limit   int
+	Offset  int
+}
+
+// String returns the clauses to be appended to a SELECT statement
+func (sq *SQLQuery) String() string {
+	sb := &strings.Builder{}
+	if len(sq.Where) != 0 {
+		sb.WriteString(" WHERE " + sq.Where)
+	}
+	if len(sq.OrderBy) != 0 {
+		sb.WriteString(" ORDER BY " + sq.OrderBy)
+	}
+	if sq.Limit > 0 {
+		sb.WriteString(" LIMIT " + strconv.Itoa(sq.Limit))
+	} else if sq.Offset > 0 {
+		sb.WriteString(" LIMIT -1")
+	}
+	if sq.Offset > 0 {
+		sb.WriteString(" OFFSET " + strconv.Itoa(sq.Offset))
+	}
+	return sb.String()
+}
+
+// Compile translates the query including its order, limit and offset
+// options. ErrNotCompilable is returned if any part of the query cannot
+// be translated into SQL returning the same records as Query.Match. The
+// order is the same as with Sort except for text values holding dates in
+// different formats which are ordered by their text instead of their time.
+func (c *SQLCompiler) Compile(q *Query) (*SQLQuery, error) {
+	where, args, err := c.CompileWhere(q)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := q.ParseOptions()
+	if err != nil {
+		return nil, err
+	} else if opts.Cursor != nil {
+		return nil, ErrNotCompilable
+	}
+
+	// values are ranked by their kind first to be consistent with Compare
+	orderBy := []string{}
+	for _, key := range opts.Order {
+		ref, err := c.field(key.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		direction := " ASC"
+		if key.Descending {
+			direction = " DESC"
+		}
+		orderBy = append(orderBy, ref.rankExpr()+direction, ref.expr+direction)
+	}
+	orderBy = append(orderBy, quoteIdent(c.idColumn())+" ASC")
+
+	return &SQLQuery{
+		Where:   where,
+		Args:    args,
+		OrderBy: strings.Join(orderBy, ", "),
+		Limit:   opts.Limit,
+		Offset:  opts.Offset,
+	}, nil
+}
+
+// CompileWhere translates the conditions of the query into a WHERE
+// expression and its bind arguments. The options of the query are ignored.
+func (c *SQLCompiler) CompileWhere(q *Query) (string, []any, error) {
+	if q == nil || len(q.Operator) == 0 {
+		return "1", nil, nil
+	}
+
+	args := []any{}
+	where, err := c.compile(q, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, args, nil
+}
+
+// CanCompile checks if the whole query can be translated into SQL
+func (c *SQLCompiler) CanCompile(q *Query) bool {
+	_, err := c.Compile(q)
+	return err == nil
+}
+
+func (c *SQLCompiler) idColumn() string {
+	if len(c.IdColumn) == 0 {
+		return "id"
+	}
+	return c.IdColumn
+}
+
+func (c *SQLCompiler) dataColumn() string {
+	if len(c.DataColumn) == 0 {
+		return quoteIdent("data")
+	}
+	return quoteIdent(c.DataColumn)
+}
+
+// fieldRef is the SQL form of a field
+type fieldRef struct {
+	// expr is the expression returning the value of the field
+	expr string
+
+	// doc and path refer to the JSON document and the path of the
+	// field for use with the JSON functions. Both are empty if the
+	// field is not stored as JSON.
+	doc  string
+	path string
+
+	// typ is the expression returning the JSON type of the value if the
+	// value is neither a JSON document nor a column (e.g. json_each)
+	typ string
+}
+
+func (ref fieldRef) isJSON() bool {
+	return len(ref.doc) != 0
+}
+
+// typeExpr returns the expression returning the type of the value. JSON
+// booleans are distinguished from numbers unlike with typeof.
+func (ref fieldRef) typeExpr() string {
+	if len(ref.typ) != 0 {
+		return ref.typ
+	} else if ref.isJSON() {
+		return fmt.Sprintf("json_type(%s, %s)", ref.doc, ref.path)
+	}
+	return fmt.Sprintf("typeof(%s)", ref.expr)
+}
+
+// isNumber returns the expression checking whether the value is a number
+func (ref fieldRef) isNumber() string {
+	return ref.typeExpr() + " IN ('integer','real')"
+}
+
+// rankExpr returns the expression ranking the kind of the value in the
+// same order as valueRank
+func (ref fieldRef) rankExpr() string {
+	return fmt.Sprintf("(CASE %s WHEN 'true' THEN 1 WHEN 'false' THEN 1 WHEN 'integer' THEN 2 WHEN 'real' THEN 2 WHEN 'text' THEN 3 WHEN 'object' THEN 4 WHEN 'array' THEN 4 WHEN 'blob' THEN 4 ELSE 0 END)", ref.typeExpr())
+}
+
+func (c *SQLCompiler) field(field string) (fieldRef, error) {
+	if len(field) == 0 {
+		return fieldRef{}, ErrNotCompilable
+	}
+
+	if len(c.elemAlias) != 0 {
+		// fields within an elem query refer to the array element
+		value := c.elemAlias + ".value"
+		if field == ElemSelf {
+			return fieldRef{expr: value, typ: c.elemAlias + ".type"}, nil
+		}
+
+		doc := fmt.Sprintf("(CASE WHEN %s.type IN ('object','array') THEN %s END)", c.elemAlias, value)
+		path := jsonPath(strings.Split(field, "."))
+		return fieldRef{
+			expr: fmt.Sprintf("json_extract(%s, %s)", doc, path),
+			doc:  doc,
+			path: path,
+		}, nil
+	}
+
+	if field == "id" {
+		return fieldRef{expr: quoteIdent(c.idColumn())}, nil
+	}
+
+	keys := strings.Split(field, ".")
+	if column, ok := c.Columns[keys[0]]; ok {
+		if len(keys) == 1 {
+			return fieldRef{expr: quoteIdent(column)}, nil
+		}
+
+		doc := quoteIdent(column)
+		path := jsonPath(keys[1:])
+		return fieldRef{
+			expr: fmt.Sprintf("json_extract(%s, %s)", doc, path),
+			doc:  doc,
+			path: path,
+		}, nil
+	}
+
+	doc := c.dataColumn()
+	path := jsonPath(keys)
+	return fieldRef{
+		expr: fmt.Sprintf("json_extract(%s, %s)", doc, path),
+		doc:  doc,
+		path: path,
+	}, nil
+}
+
+// jsonPath converts the field keys into a quoted SQLite JSON path
+// literal. Numeric keys are treated as array indices.
+func jsonPath(keys []string) string {
+	sb := &strings.Builder{}
+	sb.WriteByte('$')
+	for _, key := range keys {
+		if idx, err := strconv.Atoi(key); err == nil && idx >= 0 {
+			sb.WriteString("[" + strconv.Itoa(idx) + "]")
+		} else {
+			sb.WriteString(`."` + strings.ReplaceAll(key, `"`, `\"`) + `"`)
+		}
+	}
+	return quoteString(sb.String())
+}
+
+func quoteIdent(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func quoteString(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
+
+// sqlValue converts a scalar query value into a bind argument
+func sqlValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, float32, float64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return float64(v), nil
+		}
+		return int64(v), nil
+	case json.Number:
+		if i64, err := v.Int64(); err == nil {
+			return i64, nil
+		}
+		return v.Float64()
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	return nil, ErrNotCompilable
+}
+
+// negate negates the expression while treating NULL results as false
+// to be consistent with the inverse matchers
+func negate(expr string) string {
+	return "NOT COALESCE((" + expr + "), 0)"
+}
+
+func isNumericValue(value any) bool {
+	fl64, i64 := decodeNumberValue(value)
+	return fl64.set || i64.set
+}
+
+func (c *SQLCompiler) compile(q *Query, args *[]any) (string, error) {
+	switch q.Operator {
+	case OpAnd, OpOr:
+		queries, _ := q.Value.([]*Query)
+		if len(queries) == 0 {
+			if q.Operator == OpAnd {
+				return "1", nil
+			}
+			return "0", nil
+		}
+
+		parts := make([]string, len(queries))
+		for i, query := range queries {
+			part, err := c.compile(query, args)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+
+		return "(" + strings.Join(parts, " "+strings.ToUpper(string(q.Operator))+" ") + ")", nil
+	case OpNeq, OpNin, OpNlike, OpNilike, OpNbetween:
+		inverse := map[Operator]Operator{
+			OpNeq:      OpEq,
+			OpNin:      OpIn,
+			OpNlike:    OpLike,
+			OpNilike:   OpIlike,
+			OpNbetween: OpBetween,
+		}[q.Operator]
+
+		expr, err := c.compile(&Query{Field: q.Field, Operator: inverse, Value: q.Value}, args)
+		if err != nil {
+			return "", err
+		}
+		return negate(expr), nil
+	case OpNotnull:
+		expr, err := c.compile(&Query{Field: q.Field, Operator: OpIsnull}, args)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + expr, nil
+	}
+
+	ref, err := c.field(q.Field)
+	if err != nil {
+		return "", err
+	}
+
+	switch q.Operator {
+	case OpIsnull:
+		return ref.expr + " IS NULL", nil
+	case OpEq:
+		return c.compileEq(ref, q.Value, args)
+	case OpGt, OpGte, OpLt, OpLte:
+		sqlOp := map[Operator]string{OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}[q.Operator]
+		return c.compileComparison(ref, sqlOp, q.Value, args)
+	case OpBetween:
+		bounds, ok := toSlice(q.Value)
+		if !ok || len(bounds) != 2 {
+			return "0", nil
+		}
+
+		lower, err := c.compileComparison(ref, ">=", bounds[0], args)
+		if err != nil {
+			return "", err
+		}
+
+		upper, err := c.compileComparison(ref, "<=", bounds[1], args)
+		if err != nil {
+			return "", err
+		}
+		return "(" + lower + " AND " + upper + ")", nil
+	case OpIn:
+		values, ok := toSlice(q.Value)
+		if !ok || len(values) == 0 {
+			return "0", nil
+		}
+
+		parts := make([]string, len(values))
+		for i, value := range values {
+			part, err := c.compileEq(ref, value, args)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		return "(" + strings.Join(parts, " OR ") + ")", nil
+	case OpLike, OpIlike, OpStartsWith, OpEndsWith:
+		return c.compileText(ref, q.Operator, q.Value, args)
+	case OpContains, OpContainsAny, OpContainsAll, OpSize, OpElem:
+		return c.compileArray(ref, q, args)
+	}
+
+	return "", ErrNotCompilable
+}
+
+func (c *SQLCompiler) compileEq(ref fieldRef, value any, args *[]any) (string, error) {
+	if value == nil {
+		return ref.expr + " IS NULL", nil
+	}
+
+	if b, ok := value.(bool); ok {
+		if !ref.isJSON() {
+			*args = append(*args, b)
+			return ref.expr + " = ?", nil
+		}
+		return fmt.Sprintf("json_type(%s, %s) = '%t'", ref.doc, ref.path, b), nil
+	}
+
+	// dates are compared by their time by the matcher only if the value
+	// of the field is also a date, which cannot be checked in SQL
+	if _, ok := decodeTimeValue(value); ok {
+		return "", ErrNotCompilable
+	}
+
+	arg, err := sqlValue(value)
+	if err != nil {
+		return "", err
+	}
+
+	*args = append(*args, arg)
+	if isNumericValue(value) {
+		// booleans are stored as integers within JSON documents
+		return fmt.Sprintf("(%s AND %s = ?)", ref.isNumber(), ref.expr), nil
+	}
+	return ref.expr + " = ?", nil
+}
+
+func (c *SQLCompiler) compileComparison(ref fieldRef, sqlOp string, value any, args *[]any) (string, error) {
+	arg, err := sqlValue(value)
+	if err != nil || value == nil {
+		return "", ErrNotCompilable
+	}
+
+	if isNumericValue(value) {
+		*args = append(*args, arg)
+		return fmt.Sprintf("(%s AND %s %s ?)", ref.isNumber(), ref.expr, sqlOp), nil
+	} else if _, ok := decodeTimeValue(value); ok {
+		// julianday accepts other formats than the matcher and returns
+		// NULL instead of falling back to comparing the text
+		return "", ErrNotCompilable
+	} else if _, ok := value.(string); ok {
+		*args = append(*args, arg)
+		return fmt.Sprintf("(%s = 'text' AND %s %s ?)", ref.typeExpr(), ref.expr, sqlOp), nil
+	}
+
+	return "", ErrNotCompilable
+}
+
+func (c *SQLCompiler) compileText(ref fieldRef, op Operator, value any, args *[]any) (string, error) {
+	pattern, ok := toText(value)
+	if !ok {
+		return "", ErrNotCompilable
+	}
+
+	// only scalar values have a text form
+	text := fmt.Sprintf("(CASE WHEN typeof(%s) IN ('text','integer','real') THEN %s END)", ref.expr, ref.expr)
+	if ref.isJSON() {
+		// booleans are stored as integers within JSON documents
+		text = fmt.Sprintf("(CASE json_type(%s, %s) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' WHEN 'text' THEN %s WHEN 'integer' THEN %s WHEN 'real' THEN %s END)", ref.doc, ref.path, ref.expr, ref.expr, ref.expr)
+	}
+
+	switch op {
+	case OpStartsWith:
+		*args = append(*args, pattern, pattern)
+		return fmt.Sprintf("substr(%s, 1, length(?)) = ?", text), nil
+	case OpEndsWith:
+		if len(pattern) == 0 {
+			return text + " IS NOT NULL", nil
+		}
+		*args = append(*args, pattern, pattern)
+		return fmt.Sprintf("substr(%s, -length(?)) = ?", text), nil
+	case OpIlike:
+		if !hasWildcard(pattern) {
+			*args = append(*args, strings.ReplaceAll(pattern, "\\", ""))
+			return fmt.Sprintf("instr(lower(%s), lower(?)) > 0", text), nil
+		}
+		*args = append(*args, pattern)
+		return fmt.Sprintf(`%s LIKE ? ESCAPE '\'`, text), nil
+	}
+
+	if !hasWildcard(pattern) {
+		*args = append(*args, strings.ReplaceAll(pattern, "\\", ""))
+		return fmt.Sprintf("instr(%s, ?) > 0", text), nil
+	}
+
+	*args = append(*args, wildcardToGlob(pattern))
+	return fmt.Sprintf("%s GLOB ?", text), nil
+}
+
+// wildcardToGlob converts an SQL-style wildcard pattern into a case
+// sensitive GLOB pattern
+func wildcardToGlob(pattern string) string {
+	sb := &strings.Builder{}
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch r {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				r = runes[i]
+			}
+		case '%':
+			sb.WriteByte('*')
+			continue
+		case '_':
+			sb.WriteByte('?')
+			continue
+		}
+
+		if r == '*' || r == '?' || r == '[' {
+			sb.WriteString("[" + string(r) + "]")
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func (c *SQLCompiler) compileArray(ref fieldRef, q *Query, args *[]any) (string, error) {
+	if !ref.isJSON() {
+		return "", ErrNotCompilable
+	}
+
+	isArray := fmt.Sprintf("json_type(%s, %s) = 'array'", ref.doc, ref.path)
+	alias := fmt.Sprintf("je%d", c.elemDepth)
+	exists := func(cond string) string {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s, %s) AS %s WHERE %s)", ref.doc, ref.path, alias, cond)
+	}
+
+	elemCompiler := &SQLCompiler{
+		elemDepth: c.elemDepth + 1,
+		elemAlias: alias,
+	}
+
+	elemContains := func(value any) (string, error) {
+		cond, err := elemCompiler.compileEq(fieldRef{expr: alias + ".value"}, value, args)
+		if err != nil {
+			return "", err
+		}
+		return exists(cond), nil
+	}
+
+	switch q.Operator {
+	case OpContains:
+		cond, err := elemContains(q.Value)
+		if err != nil {
+			return "", err
+		}
+		return "(" + isArray + " AND " + cond + ")", nil
+	case OpContainsAny, OpContainsAll:
+		values, ok := toSlice(q.Value)
+		if !ok {
+			return "0", nil
+		} else if len(values) == 0 {
+			if q.Operator == OpContainsAny {
+				return "0", nil
+			}
+			return isArray, nil
+		}
+
+		joiner := " OR "
+		if q.Operator == OpContainsAll {
+			joiner = " AND "
+		}
+
+		parts := make([]string, len(values))
+		for i, value := range values {
+			cond, err := elemContains(value)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = cond
+		}
+		return "(" + isArray + " AND (" + strings.Join(parts, joiner) + "))", nil
+	case OpSize:
+		size, err := decodeIntOption("size", q.Value)
+		if err != nil {
+			return "0", nil
+		}
+
+		*args = append(*args, size)
+		return fmt.Sprintf("(%s AND json_array_length(%s, %s) = ?)", isArray, ref.doc, ref.path), nil
+	case OpElem:
+		subQuery, ok := q.Value.(*Query)
+		if !ok {
+			return "0", nil
+		}
+
+		cond, err := elemCompiler.compile(subQuery, args)
+		if err != nil {
+			return "", err
+		}
+		return "(" + isArray + " AND " + exists(cond) + ")", nil
+	}
+
+	return "", ErrNotCompilable
+}
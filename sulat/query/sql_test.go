@@ -0,0 +1,221 @@
+package query
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/go-test/deep"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLCompiler(t *testing.T) {
+	records := []testRecord{
+		{"id": "a", "title": "Hello World", "views": 120, "published": true, "published_at": "2024-03-15T10:00:00Z", "tags": []any{"go", "cms"}, "authors": []any{map[string]any{"role": "editor", "posts": 3}}},
+		{"id": "b", "title": "hello there", "views": 15.5, "published": false, "published_at": "2024-07-01", "tags": []any{"rust"}, "authors": []any{map[string]any{"role": "writer", "posts": 10}}},
+		{"id": "c", "title": "100%_done", "views": "n/a", "tags": []any{}, "meta": map[string]any{"series": []any{map[string]any{"name": "intro"}}}},
+		{"id": "d", "title": 42, "scores": []any{80, 95}},
+		{"id": "e", "title": true, "views": 1, "published": 1, "published_at": "soon", "scores": []any{true, 1}},
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE records (id TEXT PRIMARY KEY, data TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range records {
+		data, _ := json.Marshal(r)
+		if _, err := db.Exec(`INSERT INTO records (id, data) VALUES (?, ?)`, r["id"], string(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compiler := &SQLCompiler{}
+	queries := []string{
+		`eq(id "a")`,
+		`eq(views 120)`,
+		`eq(views 120.0)`,
+		`neq(views 120)`,
+		`eq(published true)`,
+		`eq(published false)`,
+		`isnull(published)`,
+		`notnull(published)`,
+		`gt(views 20)`,
+		`lte(views 20)`,
+		`gt(title "a")`,
+		`between(views [10, 200])`,
+		`nbetween(views [10, 20])`,
+		`eq(published 1)`,
+		`gt(published 0)`,
+		`gt(published_at "later")`,
+		`in(id ["a","c"])`,
+		`nin(views [120, "n/a"])`,
+		`like(title "World")`,
+		`like(title "hello%")`,
+		`like(title "100\\%\\_%")`,
+		`like(title 4)`,
+		`nlike(title "World")`,
+		`ilike(title "HELLO")`,
+		`ilike(title "hello%")`,
+		`nilike(title "hello%")`,
+		`startswith(title "Hello")`,
+		`endswith(title "there")`,
+		`endswith(title 2)`,
+		`contains(tags "go")`,
+		`containsany(tags ["rust","cms"])`,
+		`containsall(tags ["go","cms"])`,
+		`containsall(tags [])`,
+		`size(tags 0)`,
+		`size(tags 1)`,
+		`elem(authors eq(role "editor"))`,
+		`elem(authors and(eq(role "writer"),gt(posts 5)))`,
+		`elem(scores gt(_ 90))`,
+		`elem(scores eq(_ 1))`,
+		`elem(scores eq(_ true))`,
+		`elem(tags eq(_ "rust"))`,
+		`elem(meta.series eq(name "intro"))`,
+		`and(eq(published true),or(contains(tags "go"),eq(id "b")))`,
+		`or()`,
+	}
+
+	parsedQueries := []*Query{Eq("views", uint64(120)), Gt("views", uint8(20)), In("views", []any{int32(1), uint16(120)})}
+	for _, rawQuery := range queries {
+		q, err := ParseFromString(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsedQueries = append(parsedQueries, q)
+	}
+
+	// every query is matched both in memory and in SQL
+	for _, q := range parsedQueries {
+		t.Run(q.String(), func(t *testing.T) {
+			expected := []any{}
+			for _, r := range records {
+				if q.Match(r) {
+					expected = append(expected, r["id"])
+				}
+			}
+
+			compiled, err := compiler.Compile(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := db.Query("SELECT id FROM records"+compiled.String(), compiled.Args...)
+			if err != nil {
+				t.Fatalf("%s: %v", compiled.Where, err)
+			}
+			defer rows.Close()
+
+			got := []any{}
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, id)
+			}
+
+			if diff := deep.Equal(got, expected); diff != nil {
+				t.Errorf("%s: %v", compiled.Where, diff)
+			}
+		})
+	}
+
+	t.Run("Options", func(t *testing.T) {
+		q, _ := ParseFromString(`{order:["views","desc"], limit:2, offset:1}`)
+		compiled, err := compiler.Compile(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if compiled.Limit != 2 || compiled.Offset != 1 {
+			t.Errorf("Expected limit 2 and offset 1, got %d and %d", compiled.Limit, compiled.Offset)
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		// values of different kinds are ordered the same way as Compare
+		for _, field := range []string{"views", "title", "published", "tags"} {
+			for _, descending := range []bool{false, true} {
+				order := []OrderBy{{Field: field, Descending: descending}}
+				compiled, err := compiler.Compile(&Query{Options: map[string]any{"order": []any{field, map[bool]string{true: "desc", false: "asc"}[descending]}}})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				sorted := slices.Clone(records)
+				Sort(sorted, order)
+				expected := []any{}
+				for _, r := range sorted {
+					expected = append(expected, r["id"])
+				}
+
+				rows, err := db.Query("SELECT id FROM records"+compiled.String(), compiled.Args...)
+				if err != nil {
+					t.Fatalf("%s: %v", compiled.OrderBy, err)
+				}
+
+				got := []any{}
+				for rows.Next() {
+					var id string
+					if err := rows.Scan(&id); err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, id)
+				}
+				rows.Close()
+
+				if diff := deep.Equal(got, expected); diff != nil {
+					t.Errorf("%v: %v", order, diff)
+				}
+			}
+		}
+	})
+
+	t.Run("Not compilable", func(t *testing.T) {
+		notCompilable := []string{
+			`regex(title "^H")`,
+			`eq(tags ["go"])`,
+			`{cursor:"eyJ2IjpbXSwiaWQiOiJhIn0"}`,
+			// dates are only compared by their time if the field is a date
+			`eq(published_at "2024-07-01T00:00:00Z")`,
+			`gt(published_at "2024-05-01")`,
+			`between(published_at ["2024-01-01","2024-06-30"])`,
+		}
+		for _, rawQuery := range notCompilable {
+			q, err := ParseFromString(rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if compiler.CanCompile(q) {
+				t.Errorf("Expected %s not to be compilable", rawQuery)
+			}
+		}
+	})
+
+	t.Run("Mapped columns", func(t *testing.T) {
+		compiler := &SQLCompiler{IdColumn: "slug", DataColumn: "doc", Columns: map[string]string{"title": "title"}}
+		where, args, err := compiler.CompileWhere(And(Eq("id", "a"), Eq("title", "Hello"), Gt("meta.views", 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `("slug" = ? AND "title" = ? AND (json_type("doc", '$."meta"."views"') IN ('integer','real') AND json_extract("doc", '$."meta"."views"') > ?))`
+		if where != expected {
+			t.Errorf("Expected %q, got %q", expected, where)
+		}
+
+		if diff := deep.Equal(args, []any{"a", "Hello", 1}); diff != nil {
+			t.Error(diff)
+		}
+	})
+}
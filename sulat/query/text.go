@@ -0,0 +1,137 @@
+package query
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// maxCachedPatterns is the maximum number of compiled patterns kept in
+// the pattern cache before it gets cleared
+const maxCachedPatterns = 256
+
+var (
+	patternCacheMu sync.Mutex
+	patternCache   = map[string]*regexp.Regexp{}
+)
+
+// compilePattern compiles the regular expression or returns the cached
+// compiled pattern if it was compiled before
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	patternCacheMu.Lock()
+	defer patternCacheMu.Unlock()
+
+	if re, ok := patternCache[expr]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(patternCache) >= maxCachedPatterns {
+		clear(patternCache)
+	}
+	patternCache[expr] = re
+	return re, nil
+}
+
+// hasWildcard checks if the pattern contains an unescaped % or _
+func hasWildcard(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '%', '_':
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardToRegexp converts an SQL-style wildcard pattern into an anchored
+// regular expression. % matches any sequence of characters, _ matches a
+// single character and a backslash escapes the next character.
+func wildcardToRegexp(pattern string, caseInsensitive bool) string {
+	sb := &strings.Builder{}
+	sb.WriteString("(?s")
+	if caseInsensitive {
+		sb.WriteByte('i')
+	}
+	sb.WriteString(")^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+
+	sb.WriteByte('$')
+	return sb.String()
+}
+
+// matchLike matches the value against a like pattern. Patterns without
+// wildcards match any value containing the pattern.
+func matchLike(value, pattern string, caseInsensitive bool) bool {
+	if !hasWildcard(pattern) {
+		pattern = strings.ReplaceAll(pattern, "\\", "")
+		if caseInsensitive {
+			return strings.Contains(strings.ToLower(value), strings.ToLower(pattern))
+		}
+		return strings.Contains(value, pattern)
+	}
+
+	re, err := compilePattern(wildcardToRegexp(pattern, caseInsensitive))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value)
+}
+
+// textMatcher creates a matcher which compares the text form of
+// the field value against the text form of the query value
+func textMatcher(fn func(value, pattern string) bool) MatcherFunc {
+	return func(q *Query, data Accessor) bool {
+		value, ok := toText(data.Get(q.Field))
+		if !ok {
+			return false
+		}
+
+		pattern, ok := toText(q.Value)
+		if !ok {
+			return false
+		}
+
+		return fn(value, pattern)
+	}
+}
+
+var (
+	likeMatcher = textMatcher(func(value, pattern string) bool {
+		return matchLike(value, pattern, false)
+	})
+
+	ilikeMatcher = textMatcher(func(value, pattern string) bool {
+		return matchLike(value, pattern, true)
+	})
+
+	regexMatcher = textMatcher(func(value, pattern string) bool {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value)
+	})
+)
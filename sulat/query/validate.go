@@ -0,0 +1,171 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes an invalid entry of a query
+type ValidationError struct {
+	// Path is the location of the entry within the query tree expressed as
+	// dot-separated indices of the nested queries (eg. "1.0"). The root
+	// query has an empty path.
+	Path     string   `json:"path"`
+	Operator Operator `json:"operator,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (err *ValidationError) Error() string {
+	if len(err.Operator) == 0 {
+		return err.Message
+	}
+	return fmt.Sprintf("%s: %s", err.Operator, err.Message)
+}
+
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks the operators, fields and value shapes of the query
+// and its nested queries
+func (q *Query) Validate() error {
+	if q == nil {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if len(q.Operator) != 0 {
+		errs = q.validate("", errs)
+	}
+
+	if _, err := q.ParseOptions(); err != nil && !hasOptionPlaceholders(q.Options) {
+		errs = append(errs, &ValidationError{
+			Path:    "options",
+			Message: err.Error(),
+		})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (q *Query) validate(path string, errs ValidationErrors) ValidationErrors {
+	report := func(message string) ValidationErrors {
+		return append(errs, &ValidationError{
+			Path:     path,
+			Operator: q.Operator,
+			Field:    q.Field,
+			Message:  message,
+		})
+	}
+
+	if len(q.Operator) == 0 {
+		return report("operator is required")
+	} else if !q.Operator.IsSupported() {
+		return report("unsupported operator")
+	}
+
+	if q.Operator.IsLogical() {
+		if q.Value == nil {
+			return errs
+		}
+
+		queries, ok := q.Value.([]*Query)
+		if !ok {
+			return report("value must be a list of queries")
+		}
+
+		for i, query := range queries {
+			errs = query.validate(childQueryPath(path, i), errs)
+		}
+		return errs
+	}
+
+	if len(q.Field) == 0 {
+		return report("field is required")
+	} else if _, ok := q.Value.(Placeholder); ok {
+		// the shape of the value is only known once it is bound
+		return errs
+	}
+
+	switch {
+	case q.Operator.IsNull():
+		return errs
+	case q.Operator.IsIn():
+		if _, ok := toSlice(q.Value); !ok {
+			return report("value must be an array")
+		}
+	case q.Operator.IsBetween():
+		bounds, ok := toSlice(q.Value)
+		if !ok || len(bounds) != 2 {
+			return report("value must be an array of two values")
+		} else if !isScalar(bounds[0]) || !isScalar(bounds[1]) {
+			return report("bounds must be scalar values")
+		}
+	case q.Operator == OpElem:
+		subQuery, ok := q.Value.(*Query)
+		if !ok || subQuery == nil {
+			return report("value must be a query")
+		}
+		return subQuery.validate(childQueryPath(path, 0), errs)
+	case q.Operator == OpContainsAny || q.Operator == OpContainsAll:
+		if _, ok := toSlice(q.Value); !ok {
+			return report("value must be an array")
+		}
+	case q.Operator == OpSize:
+		if _, err := decodeIntOption("size", q.Value); err != nil {
+			return report(err.Error())
+		}
+	case q.Operator == OpRegex:
+		pattern, ok := q.Value.(string)
+		if !ok {
+			return report("value must be a string")
+		} else if _, err := compilePattern(pattern); err != nil {
+			return report("invalid regular expression: " + err.Error())
+		}
+	case q.Operator.IsText():
+		if _, ok := toText(q.Value); !ok {
+			return report("value must be a string")
+		}
+	case q.Operator == OpEq || q.Operator == OpNeq:
+		return errs
+	case q.Operator.IsComparative():
+		if q.Value == nil || !isScalar(q.Value) {
+			return report("value must be a scalar value")
+		}
+	}
+
+	return errs
+}
+
+func hasOptionPlaceholders(options map[string]any) bool {
+	return options != nil && (&Query{Options: options}).HasPlaceholders()
+}
+
+func childQueryPath(path string, idx int) string {
+	if len(path) == 0 {
+		return strconv.Itoa(idx)
+	}
+	return path + "." + strconv.Itoa(idx)
+}
+
+func isScalar(value any) bool {
+	if value == nil {
+		return true
+	} else if _, ok := toText(value); ok {
+		return true
+	} else if _, ok := decodeTimeValue(value); ok {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid queries", func(t *testing.T) {
+		queries := []string{
+			`eq(id 1)`,
+			`and(in(id [1,2]),between(views [1, 10]),isnull(deleted_at))`,
+			`{limit:10, order:["id","desc"]}`,
+		}
+
+		for _, rawQuery := range queries {
+			q, err := ParseFromString(rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := q.Validate(); err != nil {
+				t.Errorf("Expected %s to be valid, got %v", rawQuery, err)
+			}
+		}
+	})
+
+	t.Run("Invalid queries", func(t *testing.T) {
+		q, err := ParseFromString(`and(in(id 1),or(between(views [1]),foo(bar 1)),gt(views {a:1}),{limit:"a"})`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ValidationErrors{
+			{Path: "0", Operator: OpIn, Field: "id", Message: "value must be an array"},
+			{Path: "1.0", Operator: OpBetween, Field: "views", Message: "value must be an array of two values"},
+			{Path: "1.1", Operator: "foo", Field: "bar", Message: "unsupported operator"},
+			{Path: "2", Operator: OpGt, Field: "views", Message: "value must be a scalar value"},
+			{Path: "options", Message: "limit must be a non-negative integer"},
+		}
+
+		if diff := deep.Equal(q.Validate(), error(expected)); diff != nil {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("Missing field", func(t *testing.T) {
+		if err := Eq("", 1).Validate(); err == nil {
+			t.Fatal("Expected error for missing field")
+		}
+	})
+}
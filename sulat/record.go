@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/nedpals/sulatcms/sulat/query"
 )
 
 type fieldParser struct {
@@ -57,6 +59,10 @@ type Record struct {
 	Data       map[string]any
 	Codec      *Codec
 	Collection *Collection
+
+	// Layer is the id of the data source the record came from. It is
+	// only set by providers which combine multiple data sources.
+	Layer string `json:",omitempty"`
 }
 
 // Get returns the value of a field
@@ -69,9 +75,24 @@ func (r *Record) Get(field string) any {
 	return fp.get()
 }
 
-// Set sets the value of a field
+// Project returns a copy of the record containing only the selected fields
+func (r *Record) Project(fields []string) *Record {
+	return &Record{
+		Id:         r.Id,
+		Data:       query.Project(r, fields),
+		Codec:      r.Codec,
+		Collection: r.Collection,
+		Layer:      r.Layer,
+	}
+}
+
+// Title returns the title of the record. The id is returned if the
+// record has no title.
 func (r *Record) Title() string {
-	return r.Get("title").(string)
+	if title, ok := r.Get("title").(string); ok && len(title) != 0 {
+		return title
+	}
+	return r.Id
 }
 
 func (r *Record) Serialize() ([]byte, error) {
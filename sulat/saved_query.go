@@ -0,0 +1,68 @@
+package sulat
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+// SavedQuery is a named query of a collection. Saved queries may
+// contain placeholders (eg. $author) which are bound once executed.
+type SavedQuery struct {
+	Name         string `json:"name" db:"name"`
+	CollectionId string `json:"collection_id" db:"collection_id"`
+	Query        string `json:"query" db:"query"`
+}
+
+// Parse parses the query of the saved query
+func (sq *SavedQuery) Parse() (*query.Query, error) {
+	return query.ParseFromString(sq.Query)
+}
+
+// Parameters returns the names of the placeholders of the saved query
+func (sq *SavedQuery) Parameters() ([]string, error) {
+	q, err := sq.Parse()
+	if err != nil || q == nil {
+		return nil, err
+	}
+	return q.Placeholders(), nil
+}
+
+// Bind parses the saved query and binds the parameters into it
+func (sq *SavedQuery) Bind(params map[string]any) (*query.Query, error) {
+	q, err := sq.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	bound, err := q.Bind(params)
+	if err != nil {
+		return nil, NewResponseError(http.StatusBadRequest, err.Error())
+	}
+	return bound, nil
+}
+
+func fetchSavedQueries(savedQueries *[]*SavedQuery, collectionId string, db *sqlx.DB) error {
+	return db.Select(savedQueries, "SELECT * FROM saved_queries WHERE collection_id = ? ORDER BY name", collectionId)
+}
+
+func fetchSavedQuery(savedQuery *SavedQuery, collectionId string, name string, db *sqlx.DB) error {
+	err := db.Get(savedQuery, "SELECT * FROM saved_queries WHERE collection_id = ? AND name = ?", collectionId, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NewResponseError(http.StatusNotFound, "saved query not found")
+	}
+	return err
+}
+
+func upsertSavedQuery(savedQuery *SavedQuery, db *sqlx.DB) error {
+	_, err := db.NamedExec("INSERT INTO saved_queries (name, collection_id, query) VALUES (:name, :collection_id, :query) ON CONFLICT (name, collection_id) DO UPDATE SET query = excluded.query", savedQuery)
+	return err
+}
+
+func removeSavedQuery(savedQuery *SavedQuery, db *sqlx.DB) error {
+	_, err := db.NamedExec("DELETE FROM saved_queries WHERE name = :name AND collection_id = :collection_id", savedQuery)
+	return err
+}
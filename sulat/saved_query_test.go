@@ -0,0 +1,99 @@
+package sulat
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/nedpals/sulatcms/sulat/query"
+)
+
+func TestSavedQueries(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inst.db.Exec("INSERT INTO sites (id, name) VALUES ('default', 'Default')"); err != nil {
+		t.Fatal(err)
+	} else if _, err := inst.db.Exec("INSERT INTO collections (id, name, site_id) VALUES ('posts', 'Posts', 'default')"); err != nil {
+		t.Fatal(err)
+	}
+
+	site := &Site{instance: inst, Id: "default"}
+	collection := site.attachCollection(&Collection{Id: "posts"})
+
+	q, err := query.ParseFromString(`and(eq(author $author),gte(views $views),{limit:$limit})`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Save and find", func(t *testing.T) {
+		if _, err := collection.SaveQuery("by-author", q); err != nil {
+			t.Fatal(err)
+		}
+
+		savedQuery, err := collection.FindSavedQuery("by-author")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		params, err := savedQuery.Parameters()
+		if err != nil {
+			t.Fatal(err)
+		} else if diff := deep.Equal(params, []string{"author", "views", "limit"}); diff != nil {
+			t.Error(diff)
+		}
+
+		bound, err := savedQuery.Bind(map[string]any{
+			"author": "jane",
+			"views":  json.Number("100"),
+			"limit":  json.Number("10"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bound.String() != `and(eq(author "jane"),gte(views 100),{limit:10})` {
+			t.Errorf("Unexpected bound query: %s", bound)
+		}
+
+		_, err = savedQuery.Bind(map[string]any{"author": "jane"})
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected bad request for missing parameters, got %v", err)
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		replacement, _ := query.ParseFromString(`eq(author $author)`)
+		if _, err := collection.SaveQuery("by-author", replacement); err != nil {
+			t.Fatal(err)
+		}
+
+		savedQueries, err := collection.SavedQueries()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(savedQueries) != 1 || savedQueries[0].Query != `eq(author $author)` {
+			t.Errorf("Expected the saved query to be replaced, got %v", savedQueries)
+		}
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		invalid, _ := query.ParseFromString(`between(views [1])`)
+		if _, err := collection.SaveQuery("invalid", invalid); err == nil {
+			t.Error("Expected error for invalid query")
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		if err := collection.RemoveSavedQuery("by-author"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := collection.FindSavedQuery("by-author")
+		if rErr, ok := err.(*ResponseError); !ok || rErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
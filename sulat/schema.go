@@ -398,11 +398,21 @@ func (f RepeaterSchemaField) Properties() map[string]any {
 
 func (f RepeaterSchemaField) CastValue(input any) any {
 	// todo: add checks for content of input type
-	rt := reflect.TypeOf(input)
-	if rt.Kind() != reflect.Slice && rt.Kind() != reflect.Array {
+	if items, ok := input.([]any); ok {
+		return items
+	}
+
+	rv := reflect.ValueOf(input)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
 		return []any{}
 	}
-	return input
+
+	// typed slices (eg. []string) are converted for validation
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
 }
 
 func (f RepeaterSchemaField) Validate(input any) (bool, error) {
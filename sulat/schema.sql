@@ -29,4 +29,21 @@ CREATE TABLE IF NOT EXISTS settings (
     "group" TEXT NOT NULL,
     site_id TEXT REFERENCES sites(id) ON DELETE CASCADE,
     UNIQUE (key, "group", site_id)
-);
\ No newline at end of file
+);
+
+CREATE TABLE IF NOT EXISTS saved_queries (
+    name TEXT NOT NULL,
+    collection_id TEXT REFERENCES collections(id) ON DELETE CASCADE,
+    query TEXT NOT NULL,
+    UNIQUE (name, collection_id)
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+    site_id UNINDEXED,
+    collection_id UNINDEXED,
+    record_id UNINDEXED,
+    field UNINDEXED,
+    weight UNINDEXED,
+    content,
+    tokenize = 'porter unicode61 remove_diacritics 2'
+);
@@ -0,0 +1,436 @@
+package sulat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/exp/slices"
+)
+
+// searchWeightsKey is the collection metadata key containing the
+// weights of the fields to be indexed (eg. {"title": 5, "content": 1}).
+// All of the text fields are indexed with a weight of 1 if the weights
+// are not configured.
+const searchWeightsKey = "search_weights"
+
+const (
+	defaultSearchLimit    = 20
+	defaultHighlightStart = "<mark>"
+	defaultHighlightEnd   = "</mark>"
+	snippetTokens         = 12
+)
+
+// SearchIndex is the full-text search index of the records stored in
+// the instance database. Each indexed field of a record is stored as a
+// separate entry so that fields can be weighted differently.
+type SearchIndex struct {
+	db *sqlx.DB
+
+	// built is a map of site ids to the collections indexed since the
+	// instance was started. Collections which are not built yet are
+	// indexed on the next search.
+	built map[string]map[string]bool
+	mu    sync.Mutex
+}
+
+// SearchOptions are the options of a search
+type SearchOptions struct {
+	Limit       int
+	Offset      int
+	Collections []string
+
+	// HighlightStart and HighlightEnd surround the matched terms
+	// in the snippets. Defaults to <mark> and </mark>.
+	HighlightStart string
+	HighlightEnd   string
+}
+
+// SearchHit is a record matching the search text
+type SearchHit struct {
+	CollectionId string  `json:"collection_id"`
+	RecordId     string  `json:"record_id"`
+	Score        float64 `json:"score"`
+	Field        string  `json:"field"`
+	Snippet      string  `json:"snippet"`
+}
+
+// SearchResults are the ranked hits of a search
+type SearchResults struct {
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+	Hits   []*SearchHit `json:"hits"`
+}
+
+// Index adds the records to the index replacing their existing entries
+func (idx *SearchIndex) Index(siteId string, collection *Collection, records ...*Record) error {
+	weights := collection.SearchWeights()
+
+	tx, err := idx.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, record := range records {
+		if _, err := tx.Exec("DELETE FROM search_index WHERE site_id = ? AND collection_id = ? AND record_id = ?", siteId, collection.Id, record.Id); err != nil {
+			return err
+		}
+
+		for field, text := range searchableFields(record, weights) {
+			weight := 1.0
+			if weights != nil {
+				weight = weights[field]
+			}
+
+			if _, err := tx.Exec(
+				"INSERT INTO search_index (site_id, collection_id, record_id, field, weight, content) VALUES (?, ?, ?, ?, ?, ?)",
+				siteId, collection.Id, record.Id, field, weight, text,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Remove removes the records from the index
+func (idx *SearchIndex) Remove(siteId string, collectionId string, recordIds ...string) error {
+	tx, err := idx.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, recordId := range recordIds {
+		if _, err := tx.Exec("DELETE FROM search_index WHERE site_id = ? AND collection_id = ? AND record_id = ?", siteId, collectionId, recordId); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RemoveCollection removes all of the records of the collection from
+// the index. An empty collection id removes all records of the site.
+func (idx *SearchIndex) RemoveCollection(siteId string, collectionId string) error {
+	if len(collectionId) == 0 {
+		_, err := idx.db.Exec("DELETE FROM search_index WHERE site_id = ?", siteId)
+		return err
+	}
+
+	_, err := idx.db.Exec("DELETE FROM search_index WHERE site_id = ? AND collection_id = ?", siteId, collectionId)
+	return err
+}
+
+// isBuilt reports whether the records of the collection were indexed
+func (idx *SearchIndex) isBuilt(siteId string, collectionId string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.built[siteId][collectionId]
+}
+
+// markBuilt marks the records of the collection as indexed
+func (idx *SearchIndex) markBuilt(siteId string, collectionId string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.built == nil {
+		idx.built = map[string]map[string]bool{}
+	}
+	if idx.built[siteId] == nil {
+		idx.built[siteId] = map[string]bool{}
+	}
+	idx.built[siteId][collectionId] = true
+}
+
+// invalidate marks the collections to be indexed again on the next search.
+// An empty site id invalidates all sites and an empty collection id all
+// of the collections of the site.
+func (idx *SearchIndex) invalidate(siteId string, collectionId string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if len(siteId) == 0 {
+		idx.built = nil
+	} else if len(collectionId) == 0 {
+		delete(idx.built, siteId)
+	} else {
+		delete(idx.built[siteId], collectionId)
+	}
+}
+
+// Search finds the records of the site matching the text. Hits are
+// ranked by the sum of the weighted scores of their matching fields and
+// the snippet is taken from the best matching field.
+func (idx *SearchIndex) Search(siteId string, text string, opts SearchOptions) (*SearchResults, error) {
+	expr := searchExpression(text)
+	if len(expr) == 0 {
+		return nil, NewResponseError(http.StatusBadRequest, "search text is required")
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSearchLimit
+	}
+
+	if len(opts.HighlightStart) == 0 && len(opts.HighlightEnd) == 0 {
+		opts.HighlightStart = defaultHighlightStart
+		opts.HighlightEnd = defaultHighlightEnd
+	}
+
+	filter := "search_index MATCH ? AND site_id = ?"
+	args := []any{expr, siteId}
+	if len(opts.Collections) != 0 {
+		filter += " AND collection_id IN (?)"
+		args = append(args, opts.Collections)
+	}
+
+	countQuery, countArgs, err := sqlx.In(
+		"SELECT COUNT(*) FROM (SELECT 1 FROM search_index WHERE "+filter+" GROUP BY collection_id, record_id)",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+		Hits:   []*SearchHit{},
+	}
+	if err := idx.db.Get(&results.Total, idx.db.Rebind(countQuery), countArgs...); err != nil {
+		return nil, searchError(err)
+	}
+
+	// the matches are materialized as the ranking functions cannot be
+	// used within an aggregate. The field and snippet columns are taken
+	// from the row with the highest score as SQLite picks bare columns
+	// from the max() row.
+	hitsQuery, hitsArgs, err := sqlx.In(
+		"WITH matches AS MATERIALIZED ("+
+			"SELECT collection_id, record_id, field, -bm25(search_index) * weight AS score, "+
+			"snippet(search_index, 5, ?, ?, '…', ?) AS snippet FROM search_index WHERE "+filter+
+			") SELECT collection_id, record_id, SUM(score) AS total_score, MAX(score) AS best, field, snippet FROM matches "+
+			"GROUP BY collection_id, record_id ORDER BY total_score DESC, collection_id, record_id LIMIT ? OFFSET ?",
+		append(append([]any{opts.HighlightStart, opts.HighlightEnd, snippetTokens}, args...), opts.Limit, opts.Offset)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := idx.db.Queryx(idx.db.Rebind(hitsQuery), hitsArgs...)
+	if err != nil {
+		return nil, searchError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		hit := &SearchHit{}
+		var best float64
+		if err := rows.Scan(&hit.CollectionId, &hit.RecordId, &hit.Score, &best, &hit.Field, &hit.Snippet); err != nil {
+			return nil, err
+		}
+		results.Hits = append(results.Hits, hit)
+	}
+
+	return results, rows.Err()
+}
+
+func searchError(err error) error {
+	if strings.Contains(err.Error(), "fts5") {
+		return NewResponseError(http.StatusBadRequest, "invalid search text")
+	}
+	return err
+}
+
+// searchExpression converts the text into an FTS5 query matching all of
+// the terms. The last term is matched as a prefix for search-as-you-type.
+func searchExpression(text string) string {
+	terms := strings.Fields(text)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	if len(terms) == 0 {
+		return ""
+	}
+
+	terms[len(terms)-1] += "*"
+	return strings.Join(terms, " ")
+}
+
+// searchableFields returns the text of the fields to be indexed. Only
+// the weighted fields are returned if weights are given.
+func searchableFields(record *Record, weights map[string]float64) map[string]string {
+	fields := map[string]string{}
+	if weights != nil {
+		for field, weight := range weights {
+			if weight <= 0 {
+				continue
+			}
+
+			if text, ok := searchableText(record.Get(field)); ok {
+				fields[field] = text
+			}
+		}
+		return fields
+	}
+
+	for field, value := range record.Data {
+		if field == "id" {
+			continue
+		} else if text, ok := searchableText(value); ok {
+			fields[field] = text
+		}
+	}
+	return fields
+}
+
+func searchableText(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, len(strings.TrimSpace(v)) != 0
+	case []string:
+		return searchableText(strings.Join(v, " "))
+	case []any:
+		texts := []string{}
+		for _, item := range v {
+			if text, ok := item.(string); ok {
+				texts = append(texts, text)
+			}
+		}
+		return searchableText(strings.Join(texts, " "))
+	}
+	return "", false
+}
+
+// SearchWeights returns the weights of the fields of the collection
+// to be indexed. A nil result means all text fields are indexed.
+func (c *Collection) SearchWeights() map[string]float64 {
+	rawWeights, ok := c.Metadata[searchWeightsKey].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	weights := make(map[string]float64, len(rawWeights))
+	for field, rawWeight := range rawWeights {
+		switch w := rawWeight.(type) {
+		case float64:
+			weights[field] = w
+		case int:
+			weights[field] = float64(w)
+		case int64:
+			weights[field] = float64(w)
+		case json.Number:
+			weights[field], _ = w.Float64()
+		}
+	}
+	return weights
+}
+
+// searchIndex returns the search index of the instance of the collection.
+// A nil index is returned if the collection is not attached to an instance.
+func (c *Collection) searchIndex() *SearchIndex {
+	if c.site == nil || c.site.instance == nil {
+		return nil
+	}
+	return c.site.instance.SearchIndex()
+}
+
+// Reindex rebuilds the index entries of the records of the collection
+func (c *Collection) Reindex() error {
+	idx := c.searchIndex()
+	if idx == nil {
+		return nil
+	}
+
+	records, err := c.Source.Find(c.Id, nil, nil)
+	if rErr, ok := err.(*ResponseError); ok && rErr.StatusCode == http.StatusNotFound {
+		records = []*Record{}
+	} else if err != nil {
+		return err
+	}
+
+	if err := idx.RemoveCollection(c.site.Id, c.Id); err != nil {
+		return err
+	} else if err := idx.Index(c.site.Id, c, records...); err != nil {
+		return err
+	}
+
+	idx.markBuilt(c.site.Id, c.Id)
+	return nil
+}
+
+// indexChanges updates the index entries of the changed records. Failures
+// do not fail the changes already written to the data source and the
+// collection is indexed again on the next search instead.
+func (c *Collection) indexChanges(records []*Record, removedIds []string) {
+	idx := c.searchIndex()
+	if idx == nil {
+		return
+	}
+
+	var err error
+	if len(records) != 0 {
+		err = idx.Index(c.site.Id, c, records...)
+	}
+	if err == nil && len(removedIds) != 0 {
+		err = idx.Remove(c.site.Id, c.Id, removedIds...)
+	}
+
+	if err != nil {
+		log.Printf("search: failed to index %s/%s: %s\n", c.site.Id, c.Id, err)
+		idx.invalidate(c.site.Id, c.Id)
+	}
+}
+
+// Reindex rebuilds the index entries of the records of all collections
+func (s *Site) Reindex() error {
+	collections, err := s.Collections()
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		if err := collection.Reindex(); err != nil {
+			return fmt.Errorf("%s: %w", collection.Id, err)
+		}
+	}
+	return nil
+}
+
+// buildIndex indexes the records of the collections which are not indexed
+// yet. Collections which cannot be read are skipped until the next search.
+func (s *Site) buildIndex(collectionIds []string) {
+	collections, err := s.Collections()
+	if err != nil {
+		log.Printf("search: failed to list the collections of %s: %s\n", s.Id, err)
+		return
+	}
+
+	idx := s.instance.SearchIndex()
+	for _, collection := range collections {
+		if collection.Source == nil || idx.isBuilt(s.Id, collection.Id) {
+			continue
+		} else if len(collectionIds) != 0 && !slices.Contains(collectionIds, collection.Id) {
+			continue
+		}
+
+		if err := collection.Reindex(); err != nil {
+			log.Printf("search: failed to index %s/%s: %s\n", s.Id, collection.Id, err)
+		}
+	}
+}
+
+// Search finds the records of the site matching the text. Records stored
+// before the collections are indexed are indexed on the first search.
+func (s *Site) Search(text string, opts SearchOptions) (*SearchResults, error) {
+	s.buildIndex(opts.Collections)
+	return s.instance.SearchIndex().Search(s.Id, text, opts)
+}
@@ -0,0 +1,210 @@
+package sulat
+
+import (
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat/query"
+	"github.com/spf13/afero"
+)
+
+func TestSearchIndex(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testFs := afero.NewMemMapFs()
+	afero.WriteFile(testFs, "project/posts/draft.md", []byte("---\ntitle: Draft\n---\nNothing here yet.\n"), 0644)
+	afero.WriteFile(testFs, "project/pages/contact.md", []byte("Contact us.\n"), 0644)
+
+	dataSource, err := inst.NewDataSource("sample", "Sample", &FileDataSourceProvider{FS: testFs}, map[string]any{
+		"root": "project",
+		"collections": map[string]string{
+			"posts": "posts/*.md",
+			"pages": "pages/*.md",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site := &Site{instance: inst, Id: "default"}
+	posts := site.attachCollection(&Collection{
+		Id:       "posts",
+		Source:   dataSource,
+		Metadata: map[string]any{"search_weights": map[string]any{"title": 10.0, "content": 1.0}},
+	})
+	pages := site.attachCollection(&Collection{Id: "pages", Source: dataSource})
+
+	codec, err := inst.FindCodec("markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(collection *Collection, id string, data map[string]any) {
+		t.Helper()
+		if err := collection.Insert(&Record{Id: id, Data: data, Codec: codec}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert(posts, "gardening.md", map[string]any{"title": "Gardening tips", "content": "Water the tomatoes every morning."})
+	insert(posts, "cooking.md", map[string]any{"title": "Cooking pasta", "content": "Fresh tomatoes make the best sauce for gardening enthusiasts."})
+	insert(pages, "about.md", map[string]any{"content": "We write about cooking and gardening."})
+
+	t.Run("Ranked hits", func(t *testing.T) {
+		results, err := site.Search("gardening", SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if results.Total != 3 {
+			t.Fatalf("Expected 3 hits, got %d", results.Total)
+		} else if hit := results.Hits[0]; hit.RecordId != "gardening.md" || hit.Field != "title" {
+			t.Fatalf("Expected the title match to rank first, got %+v", hit)
+		} else if hit.Snippet != "<mark>Gardening</mark> tips" {
+			t.Errorf("Unexpected snippet: %q", hit.Snippet)
+		}
+
+		for i := 1; i < len(results.Hits); i++ {
+			if results.Hits[i].Score > results.Hits[i-1].Score {
+				t.Errorf("Expected hits to be sorted by score, got %+v", results.Hits)
+			}
+		}
+	})
+
+	t.Run("Pagination and filters", func(t *testing.T) {
+		results, err := site.Search("garden", SearchOptions{Limit: 1, Offset: 1, Collections: []string{"posts"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if results.Total != 2 || len(results.Hits) != 1 {
+			t.Fatalf("Expected 1 of 2 hits, got %d of %d", len(results.Hits), results.Total)
+		} else if results.Hits[0].RecordId != "cooking.md" {
+			t.Errorf("Expected cooking.md, got %s", results.Hits[0].RecordId)
+		}
+	})
+
+	t.Run("Special characters", func(t *testing.T) {
+		results, err := site.Search(`tomatoes" OR (`, SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		} else if results.Total != 0 {
+			t.Errorf("Expected no hits, got %d", results.Total)
+		}
+	})
+
+	t.Run("Update and delete", func(t *testing.T) {
+		if err := posts.Update(&Record{Id: "cooking.md", Data: map[string]any{"title": "Cooking rice", "content": "Rinse the rice twice."}, Codec: codec}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := site.Search("tomatoes", SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		} else if results.Total != 1 || results.Hits[0].RecordId != "gardening.md" {
+			t.Fatalf("Expected the updated record to be reindexed, got %+v", results.Hits)
+		}
+
+		if err := posts.Delete(query.Eq("id", "gardening.md"), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		results, err = site.Search("tomatoes", SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		} else if results.Total != 0 {
+			t.Fatalf("Expected the deleted record to be removed, got %+v", results.Hits)
+		}
+	})
+
+	t.Run("Reindex", func(t *testing.T) {
+		if err := inst.SearchIndex().RemoveCollection(site.Id, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := pages.Reindex(); err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := site.Search("cooking", SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		} else if results.Total != 1 || results.Hits[0].CollectionId != "pages" {
+			t.Fatalf("Expected only the reindexed collection, got %+v", results.Hits)
+		}
+	})
+}
+
+func TestSearchIndexBuild(t *testing.T) {
+	inst, err := NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testFs := afero.NewMemMapFs()
+	afero.WriteFile(testFs, "project/posts/gardening.md", []byte("---\ntitle: Gardening tips\n---\nWater the tomatoes.\n"), 0644)
+
+	dataSource, err := inst.NewDataSource("sample", "Sample", &FileDataSourceProvider{FS: testFs}, map[string]any{
+		"root":        "project",
+		"collections": map[string]string{"posts": "posts/*.md"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site := &Site{instance: inst, Id: "default"}
+	posts := site.attachCollection(&Collection{Id: "posts", Source: dataSource})
+	site.collections = []*Collection{posts}
+
+	search := func(t *testing.T, text string) []string {
+		t.Helper()
+		results, err := site.Search(text, SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []string{}
+		for _, hit := range results.Hits {
+			ids = append(ids, hit.RecordId)
+		}
+		return ids
+	}
+
+	t.Run("Existing records", func(t *testing.T) {
+		if ids := search(t, "tomatoes"); len(ids) != 1 || ids[0] != "gardening.md" {
+			t.Fatalf("Expected the stored record to be indexed on the first search, got %v", ids)
+		}
+	})
+
+	t.Run("Reinitialized data sources", func(t *testing.T) {
+		afero.WriteFile(testFs, "project/posts/cooking.md", []byte("---\ntitle: Cooking\n---\nSlice the tomatoes.\n"), 0644)
+		if err := dataSource.Reinitialize(); err != nil {
+			t.Fatal(err)
+		}
+
+		if ids := search(t, "tomatoes"); len(ids) != 2 {
+			t.Fatalf("Expected the reloaded records to be indexed, got %v", ids)
+		}
+	})
+
+	t.Run("Index failures", func(t *testing.T) {
+		if _, err := inst.db.Exec("DROP TABLE search_index"); err != nil {
+			t.Fatal(err)
+		}
+
+		// the record is written even if it cannot be indexed
+		if err := posts.Insert(&Record{Id: "pruning.md", Data: map[string]any{"title": "Pruning roses", "content": "Cut the stems."}}, nil); err != nil {
+			t.Fatalf("Expected the write to succeed, got %v", err)
+		} else if _, err := posts.Get("pruning.md", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := inst.db.Exec(dbSchema); err != nil {
+			t.Fatal(err)
+		} else if ids := search(t, "roses"); len(ids) != 1 || ids[0] != "pruning.md" {
+			t.Fatalf("Expected the collection to be indexed again, got %v", ids)
+		}
+	})
+}
@@ -107,6 +107,11 @@ func (s *Site) RemoveCollection(collectionId string) error {
 		return err
 	}
 
+	if err := s.instance.SearchIndex().RemoveCollection(s.Id, collection.Id); err != nil {
+		return err
+	}
+	s.instance.SearchIndex().invalidate(s.Id, collection.Id)
+
 	s.collections = slices.DeleteFunc(s.collections, func(c *Collection) bool {
 		return c.Id == collectionId
 	})
@@ -0,0 +1,389 @@
+// Package sulattest provides a conformance suite for data source providers.
+//
+// A provider implementation runs the suite against itself from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		sulattest.RunConformance(t, sulattest.Harness{
+//			NewProvider: func(t *testing.T, fixtures map[string][]*sulat.Record) sulat.DataSourceProvider {
+//				// create and initialize the provider with the fixtures
+//			},
+//		})
+//	}
+package sulattest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat"
+	"github.com/nedpals/sulatcms/sulat/query"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// PostsCollection is the collection seeded with the posts fixtures
+	PostsCollection = "posts"
+
+	// EmptyCollection is a collection without records
+	EmptyCollection = "empty"
+
+	// UnknownCollection is a collection which the provider must not know
+	UnknownCollection = "unknown"
+)
+
+// Harness describes how the suite creates the provider under test
+type Harness struct {
+	// NewProvider returns an initialized provider containing the collections
+	// of the fixtures seeded with their records. It is called once for each
+	// test so that the tests do not affect each other.
+	NewProvider func(t *testing.T, fixtures map[string][]*sulat.Record) sulat.DataSourceProvider
+
+	// RecordId converts the record ids used by the suite into the ids
+	// supported by the provider (e.g. by appending a file extension).
+	// The ids are used as is if nil.
+	RecordId func(id string) string
+
+	// Skip is a list of the names of the tests which should be skipped
+	Skip []string
+}
+
+// Fixtures returns the records the collections are seeded with
+func (h Harness) Fixtures() map[string][]*sulat.Record {
+	return map[string][]*sulat.Record{
+		PostsCollection: {
+			{Id: h.id("alpha"), Data: map[string]any{"title": "Alpha", "author": "jane", "views": 10, "published": true, "tags": []any{"go", "cms"}, "summary": "First post"}},
+			{Id: h.id("beta"), Data: map[string]any{"title": "Beta", "author": "john", "views": 25, "published": false, "tags": []any{"rust"}}},
+			{Id: h.id("gamma"), Data: map[string]any{"title": "Gamma", "author": "jane", "views": 40, "published": true, "tags": []any{}}},
+			{Id: h.id("delta"), Data: map[string]any{"title": "Delta", "author": "jim", "views": 5, "published": true, "tags": []any{"go"}}},
+		},
+		EmptyCollection: {},
+	}
+}
+
+func (h Harness) id(id string) string {
+	if h.RecordId == nil {
+		return id
+	}
+	return h.RecordId(id)
+}
+
+func (h Harness) ids(ids ...string) []string {
+	converted := make([]string, len(ids))
+	for i, id := range ids {
+		converted[i] = h.id(id)
+	}
+	return converted
+}
+
+func (h Harness) run(t *testing.T, name string, test func(t *testing.T, provider sulat.DataSourceProvider)) {
+	t.Run(name, func(t *testing.T) {
+		if slices.Contains(h.Skip, name) {
+			t.Skip("skipped by the harness")
+		}
+		test(t, h.NewProvider(t, h.Fixtures()))
+	})
+}
+
+// RunConformance runs the conformance suite against the provider created
+// by the harness
+func RunConformance(t *testing.T, h Harness) {
+	if h.NewProvider == nil {
+		t.Fatal("harness has no NewProvider function")
+	}
+
+	h.run(t, "Get", func(t *testing.T, provider sulat.DataSourceProvider) {
+		record, err := provider.Get(PostsCollection, h.id("alpha"), nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if record.Id != h.id("alpha") || record.Get("title") != "Alpha" {
+			t.Fatalf("Unexpected record: %s %v", record.Id, record.Data)
+		}
+
+		_, err = provider.Get(PostsCollection, h.id("omega"), nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		_, err = provider.Get(UnknownCollection, h.id("alpha"), nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+	})
+
+	h.run(t, "Find operators", func(t *testing.T, provider sulat.DataSourceProvider) {
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{`eq(author "jane")`, h.ids("alpha", "gamma")},
+			{`eq(published false)`, h.ids("beta")},
+			{fmt.Sprintf(`eq(id %q)`, h.id("delta")), h.ids("delta")},
+			{`neq(author "jane")`, h.ids("beta", "delta")},
+			{`gt(views 10)`, h.ids("beta", "gamma")},
+			{`gte(views 10)`, h.ids("alpha", "beta", "gamma")},
+			{`lt(views 10)`, h.ids("delta")},
+			{`lte(views 10)`, h.ids("alpha", "delta")},
+			{`in(author ["john","jim"])`, h.ids("beta", "delta")},
+			{`nin(author ["john","jim"])`, h.ids("alpha", "gamma")},
+			{`between(views [10, 25])`, h.ids("alpha", "beta")},
+			{`nbetween(views [10, 25])`, h.ids("gamma", "delta")},
+			{`like(title "%ta")`, h.ids("beta", "delta")},
+			{`ilike(title "a%")`, h.ids("alpha")},
+			{`startswith(title "G")`, h.ids("gamma")},
+			{`endswith(title "pha")`, h.ids("alpha")},
+			{`regex(title "^[AB]")`, h.ids("alpha", "beta")},
+			{`contains(tags "go")`, h.ids("alpha", "delta")},
+			{`containsany(tags ["rust","cms"])`, h.ids("alpha", "beta")},
+			{`containsall(tags ["go","cms"])`, h.ids("alpha")},
+			{`size(tags 0)`, h.ids("gamma")},
+			{`elem(tags eq(_ "rust"))`, h.ids("beta")},
+			{`notnull(summary)`, h.ids("alpha")},
+			{`and(eq(author "jane"),gt(views 20))`, h.ids("gamma")},
+			{`or(eq(author "jim"),contains(tags "rust"))`, h.ids("beta", "delta")},
+			{`and(eq(published true),or(contains(tags "cms"),lt(views 10)))`, h.ids("alpha", "delta")},
+		}
+
+		for _, tt := range tests {
+			ids, err := findIds(provider, PostsCollection, tt.query)
+			if err != nil {
+				t.Errorf("%s: %v", tt.query, err)
+				continue
+			}
+
+			slices.Sort(tt.expected)
+			if !slices.Equal(ids, tt.expected) {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.expected, ids)
+			}
+		}
+	})
+
+	h.run(t, "Find options", func(t *testing.T, provider sulat.DataSourceProvider) {
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{`gt(views 0, {order:["views","desc"]})`, h.ids("gamma", "beta", "alpha", "delta")},
+			{`{order:["title"], limit:2}`, h.ids("alpha", "beta")},
+			{`{order:["title"], limit:2, offset:3}`, h.ids("gamma")},
+			{`eq(published true, {order:["author","views","desc"]})`, h.ids("gamma", "alpha", "delta")},
+			{`gt(views 0, {limit:2, offset:10})`, []string{}},
+		}
+
+		for _, tt := range tests {
+			ids, err := findIds(provider, PostsCollection, tt.query)
+			if err != nil {
+				t.Errorf("%s: %v", tt.query, err)
+			} else if !slices.Equal(ids, tt.expected) {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.expected, ids)
+			}
+		}
+
+		q, _ := query.ParseFromString(`{select:["title"], order:["title"], limit:1}`)
+		records, err := provider.Find(PostsCollection, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(records) != 1 || records[0].Get("title") != "Alpha" || records[0].Get("author") != nil {
+			t.Fatalf("Expected a projected record, got %v", records)
+		}
+	})
+
+	h.run(t, "Find errors", func(t *testing.T, provider sulat.DataSourceProvider) {
+		records, err := provider.Find(EmptyCollection, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(records) != 0 {
+			t.Fatalf("Expected no records, got %d", len(records))
+		}
+
+		q, _ := query.ParseFromString(`eq(author "nobody")`)
+		_, err = provider.Find(PostsCollection, q, nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		_, err = provider.Find(UnknownCollection, nil, nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		_, err = provider.Find(PostsCollection, &query.Query{Operator: "unknown", Field: "title", Value: "Alpha"}, nil)
+		ExpectStatus(t, err, http.StatusBadRequest)
+
+		_, err = provider.Find(PostsCollection, query.Eq("title", "Alpha").WithOption("limit", "many"), nil)
+		ExpectStatus(t, err, http.StatusBadRequest)
+	})
+
+	h.run(t, "Count", func(t *testing.T, provider sulat.DataSourceProvider) {
+		counter, ok := provider.(sulat.DataSourceCounter)
+		if !ok {
+			t.Skip("provider does not implement DataSourceCounter")
+		}
+
+		for rawQuery, expected := range map[string]int{
+			`eq(author "jane", {limit:1})`: 2,
+			`regex(title "^[BG]")`:         2,
+			`eq(author "nobody")`:          0,
+		} {
+			q, _ := query.ParseFromString(rawQuery)
+			if count, err := counter.Count(PostsCollection, q, nil); err != nil {
+				t.Errorf("%s: %v", rawQuery, err)
+			} else if count != expected {
+				t.Errorf("%s: expected %d, got %d", rawQuery, expected, count)
+			}
+		}
+
+		_, err := counter.Count(UnknownCollection, nil, nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+	})
+
+	h.run(t, "Insert", func(t *testing.T, provider sulat.DataSourceProvider) {
+		if err := provider.Insert(PostsCollection, &sulat.Record{Id: h.id("epsilon"), Data: map[string]any{"title": "Epsilon", "views": 1}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if record, err := provider.Get(PostsCollection, h.id("epsilon"), nil); err != nil {
+			t.Fatal(err)
+		} else if record.Get("title") != "Epsilon" {
+			t.Fatalf("Expected inserted record, got %v", record.Data)
+		}
+
+		if ids, err := findIds(provider, PostsCollection, `lt(views 10)`); err != nil {
+			t.Fatal(err)
+		} else if len(ids) != 2 {
+			t.Fatalf("Expected the inserted record to be found, got %v", ids)
+		}
+
+		err := provider.Insert(PostsCollection, &sulat.Record{Id: h.id("alpha"), Data: map[string]any{"title": "Duplicate"}}, nil)
+		ExpectStatus(t, err, http.StatusConflict)
+
+		err = provider.Insert(PostsCollection, &sulat.Record{Data: map[string]any{"title": "No id"}}, nil)
+		ExpectStatus(t, err, http.StatusBadRequest)
+
+		err = provider.Insert(UnknownCollection, &sulat.Record{Id: h.id("alpha"), Data: map[string]any{"title": "Alpha"}}, nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		if err := provider.Insert(EmptyCollection, &sulat.Record{Id: h.id("first"), Data: map[string]any{"title": "First"}}, nil); err != nil {
+			t.Fatal(err)
+		} else if records, err := provider.Find(EmptyCollection, nil, nil); err != nil || len(records) != 1 {
+			t.Fatalf("Expected 1 record in the empty collection, got %d (%v)", len(records), err)
+		}
+	})
+
+	h.run(t, "Update", func(t *testing.T, provider sulat.DataSourceProvider) {
+		if err := provider.Update(PostsCollection, &sulat.Record{Id: h.id("beta"), Data: map[string]any{"title": "Beta 2", "author": "john", "views": 30}}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if record, err := provider.Get(PostsCollection, h.id("beta"), nil); err != nil {
+			t.Fatal(err)
+		} else if record.Get("title") != "Beta 2" {
+			t.Fatalf("Expected updated record, got %v", record.Data)
+		}
+
+		if ids, err := findIds(provider, PostsCollection, `eq(title "Beta 2")`); err != nil {
+			t.Fatal(err)
+		} else if !slices.Equal(ids, h.ids("beta")) {
+			t.Fatalf("Expected the updated record to be found, got %v", ids)
+		}
+
+		err := provider.Update(PostsCollection, &sulat.Record{Id: h.id("omega"), Data: map[string]any{"title": "Omega"}}, nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		err = provider.Update(UnknownCollection, &sulat.Record{Id: h.id("beta"), Data: map[string]any{"title": "Beta"}}, nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+	})
+
+	h.run(t, "Delete", func(t *testing.T, provider sulat.DataSourceProvider) {
+		err := provider.Delete(PostsCollection, nil, nil)
+		ExpectStatus(t, err, http.StatusBadRequest)
+
+		err = provider.Delete(UnknownCollection, query.Eq("author", "jane"), nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		q, _ := query.ParseFromString(`or(eq(author "jane"),regex(title "^Del"))`)
+		if err := provider.Delete(PostsCollection, q, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if ids, err := findIds(provider, PostsCollection, ""); err != nil {
+			t.Fatal(err)
+		} else if !slices.Equal(ids, h.ids("beta")) {
+			t.Fatalf("Expected only beta to remain, got %v", ids)
+		}
+
+		_, err = provider.Get(PostsCollection, h.id("alpha"), nil)
+		ExpectStatus(t, err, http.StatusNotFound)
+
+		// deleting nothing is not an error
+		if err := provider.Delete(PostsCollection, query.Eq("author", "nobody"), nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.run(t, "Concurrency", func(t *testing.T, provider sulat.DataSourceProvider) {
+		const writers = 8
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*3)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				id := h.id(fmt.Sprintf("concurrent-%d", i))
+				if err := provider.Insert(PostsCollection, &sulat.Record{Id: id, Data: map[string]any{"title": "Concurrent", "views": i}}, nil); err != nil {
+					errs <- err
+					return
+				}
+
+				if _, err := provider.Find(PostsCollection, query.Eq("title", "Concurrent"), nil); err != nil {
+					errs <- err
+				}
+
+				if err := provider.Update(PostsCollection, &sulat.Record{Id: id, Data: map[string]any{"title": "Concurrent", "views": i + 100}}, nil); err != nil {
+					errs <- err
+				}
+			}(i)
+		}
+
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		if ids, err := findIds(provider, PostsCollection, `gte(views 100)`); err != nil {
+			t.Fatal(err)
+		} else if len(ids) != writers {
+			t.Fatalf("Expected %d updated records, got %v", writers, ids)
+		}
+	})
+}
+
+// findIds returns the ids of the records matching the query string
+func findIds(provider sulat.DataSourceProvider, collectionId string, rawQuery string) ([]string, error) {
+	q, err := query.ParseFromString(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := provider.Find(collectionId, q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.Id
+	}
+
+	// records are compared in order only if the query has an order
+	if !strings.Contains(rawQuery, "order:") {
+		slices.Sort(ids)
+	}
+	return ids, nil
+}
+
+// ExpectStatus fails the test if the error is not a response error
+// with the status code
+func ExpectStatus(t *testing.T, err error, statusCode int) {
+	t.Helper()
+	if rErr, ok := err.(*sulat.ResponseError); !ok || rErr.StatusCode != statusCode {
+		t.Errorf("Expected error with status %d, got %v", statusCode, err)
+	}
+}
@@ -0,0 +1,128 @@
+package sulattest
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/nedpals/sulatcms/sulat"
+	"github.com/spf13/afero"
+)
+
+func newInstance(t *testing.T) *sulat.Instance {
+	t.Helper()
+	inst, err := sulat.NewInstance("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inst
+}
+
+func initializeProvider(t *testing.T, provider sulat.DataSourceProvider, config map[string]any) sulat.DataSourceProvider {
+	t.Helper()
+	provider, err := provider.WithConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := provider.Initialize(newInstance(t)); err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func seedProvider(t *testing.T, provider sulat.DataSourceProvider, fixtures map[string][]*sulat.Record) {
+	t.Helper()
+	for collectionId, records := range fixtures {
+		for _, record := range records {
+			if err := provider.Insert(collectionId, record, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestMemoryDataSourceProviderConformance(t *testing.T) {
+	RunConformance(t, Harness{
+		NewProvider: func(t *testing.T, fixtures map[string][]*sulat.Record) sulat.DataSourceProvider {
+			return initializeProvider(t, &sulat.MemoryDataSourceProvider{Fixtures: fixtures}, map[string]any{})
+		},
+	})
+}
+
+func TestFileDataSourceProviderConformance(t *testing.T) {
+	RunConformance(t, Harness{
+		NewProvider: func(t *testing.T, fixtures map[string][]*sulat.Record) sulat.DataSourceProvider {
+			fs := afero.NewMemMapFs()
+			collections := map[string]any{}
+			for collectionId, records := range fixtures {
+				collections[collectionId] = collectionId + "/*.json"
+				if err := fs.MkdirAll(filepath.Join("site", collectionId), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, record := range records {
+					data, err := json.Marshal(record.Data)
+					if err != nil {
+						t.Fatal(err)
+					} else if err := afero.WriteFile(fs, filepath.Join("site", collectionId, record.Id), data, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			return initializeProvider(t, &sulat.FileDataSourceProvider{FS: fs}, map[string]any{
+				"root":        "site",
+				"collections": collections,
+			})
+		},
+		RecordId: func(id string) string {
+			return id + ".json"
+		},
+	})
+}
+
+func TestSQLiteDataSourceProviderConformance(t *testing.T) {
+	RunConformance(t, Harness{
+		NewProvider: func(t *testing.T, fixtures map[string][]*sulat.Record) sulat.DataSourceProvider {
+			collections := map[string]any{}
+			for collectionId := range fixtures {
+				collections[collectionId] = ""
+			}
+
+			provider := initializeProvider(t, &sulat.SQLiteDataSourceProvider{}, map[string]any{
+				"path":        ":memory:",
+				"collections": collections,
+			})
+			seedProvider(t, provider, fixtures)
+			return provider
+		},
+	})
+}
+
+func TestCompositeDataSourceProviderConformance(t *testing.T) {
+	RunConformance(t, Harness{
+		NewProvider: func(t *testing.T, fixtures map[string][]*sulat.Record) sulat.DataSourceProvider {
+			inst := newInstance(t)
+			if _, err := inst.NewDataSource("local", "Local", &sulat.MemoryDataSourceProvider{Fixtures: fixtures}, map[string]any{}); err != nil {
+				t.Fatal(err)
+			}
+
+			// the read-only layer contains the collections but no records
+			// since its records cannot be deleted
+			emptyFixtures := map[string][]*sulat.Record{}
+			for collectionId := range fixtures {
+				emptyFixtures[collectionId] = []*sulat.Record{}
+			}
+			if _, err := inst.NewDataSource("shared", "Shared", &sulat.MemoryDataSourceProvider{Fixtures: emptyFixtures}, map[string]any{}); err != nil {
+				t.Fatal(err)
+			}
+
+			dataSource, err := inst.NewDataSource("composite", "Composite", &sulat.CompositeDataSourceProvider{}, map[string]any{
+				"layers": []string{"local", "shared"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return dataSource.DataSourceProvider
+		},
+	})
+}